@@ -9,8 +9,20 @@ import (
 	"github.com/openai/openai-go"
 )
 
+// DefaultModel is the chat model used when AIClient.Model is not set.
+const DefaultModel = openai.ChatModelGPT4o2024_08_06
+
 type AIClient struct {
 	Client *openai.Client
+	Model  openai.ChatModel
+}
+
+// ChatModel returns the model used for completions.
+func (ai *AIClient) ChatModel() openai.ChatModel {
+	if ai.Model == "" {
+		return DefaultModel
+	}
+	return ai.Model
 }
 
 func (ai *AIClient) FindTrackSamples(ctx context.Context, song, artist string) (*config.SampledTrack, error) {
@@ -38,7 +50,7 @@ func (ai *AIClient) FindTrackSamples(ctx context.Context, song, artist string) (
 				JSONSchema: openai.F(schemaParam),
 			},
 		),
-		Model: openai.F(openai.ChatModelGPT4o2024_08_06),
+		Model: openai.F(ai.ChatModel()),
 	})
 
 	if err != nil {
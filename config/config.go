@@ -3,11 +3,11 @@ package config
 import (
 	"context"
 	"log"
+	"net/http"
 	"sync"
 
 	"cloud.google.com/go/firestore"
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
-	"github.com/ericflores108/spotify/auth"
 	"github.com/ericflores108/spotify/genius"
 	"github.com/ericflores108/spotify/logger"
 	"github.com/ericflores108/spotify/spotify"
@@ -25,57 +25,95 @@ type AppConfig struct {
 	SpotifyClient       *spotify.AuthClient
 }
 
+// Options controls where GetConfig reads configuration from and which cloud clients it creates.
+type Options struct {
+	// Local reads secrets from TITLED_* environment variables and File instead of Secret Manager,
+	// and keeps starting when Genius or Spotify cannot be reached.
+	Local bool
+	// File is an optional YAML file of secrets used in local mode.
+	File string
+	// Firestore creates a Firestore client.
+	Firestore bool
+	// GeniusBaseURL replaces the Genius API, e.g. with a local stand-in server. Empty means
+	// genius.DefaultBaseURL.
+	GeniusBaseURL string
+}
+
 var (
 	instance *AppConfig
 	once     sync.Once
 )
 
+// Close releases the cloud clients created by GetConfig.
+func (c *AppConfig) Close() {
+	if c.SecretManagerClient != nil {
+		c.SecretManagerClient.Close()
+	}
+}
+
 // GetConfig initializes and returns a singleton AppConfig instance
-func GetConfig(ctx context.Context) *AppConfig {
+func GetConfig(ctx context.Context, options Options) *AppConfig {
 	once.Do(func() {
+		var (
+			source              Source
+			secretManagerClient *secretmanager.Client
+			err                 error
+		)
 
-		// Initialize Secret Manager client
-		secretManagerClient, err := secretmanager.NewClient(ctx)
-		if err != nil {
-			logger.LogError("failed to create secret manager client: %v", err)
-			log.Fatal(err) // Exit on failure
-		}
-
-		// Retrieve secrets
-		clientID, err := auth.GetSecret(ctx, secretManagerClient, GoogleProjectID, SpotifyClientID)
-		if err != nil {
-			logger.LogError("failed to retrieve SpotifyClientID secret: %v", err)
-			log.Fatal(err)
-		}
-
-		clientSecret, err := auth.GetSecret(ctx, secretManagerClient, GoogleProjectID, SpotifySecretID)
-		if err != nil {
-			logger.LogError("failed to retrieve SpotifySecretID secret: %v", err)
-			log.Fatal(err)
-		}
-
-		openAISecret, err := auth.GetSecret(ctx, secretManagerClient, GoogleProjectID, OpenAIApiKeyID)
-		if err != nil {
-			logger.LogError("failed to retrieve OpenAIApiKeyID secret: %v", err)
-			log.Fatal(err)
+		if options.Local {
+			chain := ChainSource{EnvSource{}}
+			if options.File != "" {
+				fileSource, err := NewFileSource(options.File)
+				if err != nil {
+					logger.LogError(ctx, "failed to load config file: %v", err)
+					log.Fatal(err)
+				}
+				chain = append(chain, fileSource)
+			}
+			source = chain
+		} else {
+			// Initialize Secret Manager client
+			secretManagerClient, err = secretmanager.NewClient(ctx)
+			if err != nil {
+				logger.LogError(ctx, "failed to create secret manager client: %v", err)
+				log.Fatal(err) // Exit on failure
+			}
+			source = &SecretManagerSource{Client: secretManagerClient, ProjectID: GoogleProjectID}
 		}
 
-		geniusClientSecret, err := auth.GetSecret(ctx, secretManagerClient, GoogleProjectID, GeniusClientSecret)
-		if err != nil {
-			logger.LogError("failed to retrieve GeniusClientSecret secret: %v", err)
-			log.Fatal(err)
+		// getSecret exits unless running locally, where a missing secret only disables the
+		// integration that needs it
+		getSecret := func(name string) string {
+			value, err := source.Get(ctx, name)
+			if err != nil {
+				logger.LogError(ctx, "failed to retrieve %s secret: %v", name, err)
+				if !options.Local {
+					log.Fatal(err)
+				}
+			}
+			return value
 		}
 
-		geniusClientID, err := auth.GetSecret(ctx, secretManagerClient, GoogleProjectID, GeniusClientID)
-		if err != nil {
-			logger.LogError("failed to retrieve GeniusClientID secret: %v", err)
-			log.Fatal(err)
-		}
+		// Retrieve secrets
+		clientID := getSecret(SpotifyClientID)
+		clientSecret := getSecret(SpotifySecretID)
+		openAISecret := getSecret(OpenAIApiKeyID)
+		geniusClientSecret := getSecret(GeniusClientSecret)
+		geniusClientID := getSecret(GeniusClientID)
 
-		geniusClient, err := genius.NewClient(geniusClientID, geniusClientSecret)
+		geniusClient, err := genius.NewClient(ctx, options.GeniusBaseURL, geniusClientID, geniusClientSecret)
 		if err != nil {
-			logger.LogError("failed to retrieve geniusClient: %v", err)
-			log.Fatal(err)
+			logger.LogError(ctx, "failed to retrieve geniusClient: %v", err)
+			if !options.Local {
+				log.Fatal(err)
+			}
+			// authenticates on first use instead, in case Genius was only briefly unreachable
+			geniusClient = &genius.GeniusClient{
+				Client:       &http.Client{},
+				BaseURL:      options.GeniusBaseURL,
+				ClientID:     geniusClientID,
+				ClientSecret: geniusClientSecret,
+			}
 		}
 
 		// Initialize OpenAI client
@@ -84,17 +122,23 @@ func GetConfig(ctx context.Context) *AppConfig {
 		)
 
 		// Initialize Firestore client
-		firestoreClient, err := firestore.NewClient(ctx, GoogleProjectID)
-		if err != nil {
-			logger.LogError("failed to create Firestore client: %v", err)
-			log.Fatal(err)
+		var firestoreClient *firestore.Client
+		if options.Firestore {
+			firestoreClient, err = firestore.NewClient(ctx, GoogleProjectID)
+			if err != nil {
+				logger.LogError(ctx, "failed to create Firestore client: %v", err)
+				log.Fatal(err)
+			}
 		}
 
 		// Initialize Client Credentials Flow https://developer.spotify.com/documentation/web-api/tutorials/client-credentials-flow
-		spotifyClient, err := spotify.NewSpotifyClient(clientID, clientSecret)
+		spotifyClient, err := spotify.NewSpotifyClient(ctx, clientID, clientSecret)
 		if err != nil {
-			logger.LogError("failed to create Spotify client: %v", err)
-			log.Fatal(err)
+			logger.LogError(ctx, "failed to create Spotify client: %v", err)
+			if !options.Local {
+				log.Fatal(err)
+			}
+			spotifyClient = &spotify.AuthClient{Client: &http.Client{}}
 		}
 
 		// Assign to singleton instance
@@ -108,7 +152,7 @@ func GetConfig(ctx context.Context) *AppConfig {
 			SpotifyClient:       spotifyClient,
 		}
 
-		logger.LogInfo("Configuration initialized successfully.")
+		logger.LogInfo(ctx, "Configuration initialized successfully.")
 	})
 	return instance
 }
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"github.com/ericflores108/spotify/auth"
+	"gopkg.in/yaml.v3"
+)
+
+// Source provides secrets and settings by name, e.g. SpotifyClientID.
+type Source interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// ErrNotSet is returned by a Source that has no value for a name.
+var ErrNotSet = errors.New("not set")
+
+// SecretManagerSource reads values from Google Secret Manager.
+type SecretManagerSource struct {
+	Client    *secretmanager.Client
+	ProjectID string
+}
+
+func (s *SecretManagerSource) Get(ctx context.Context, name string) (string, error) {
+	return auth.GetSecret(ctx, s.Client, s.ProjectID, name)
+}
+
+// EnvSource reads values from environment variables named TITLED_ followed by the
+// upper-cased name, e.g. TITLED_SPOTIFY_CLIENT_ID.
+type EnvSource struct{}
+
+func (EnvSource) Get(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(EnvName(name))
+	if !ok || value == "" {
+		return "", fmt.Errorf("%s: %w", EnvName(name), ErrNotSet)
+	}
+	return value, nil
+}
+
+// EnvName returns the environment variable EnvSource reads a name from.
+func EnvName(name string) string {
+	return "TITLED_" + strings.ToUpper(name)
+}
+
+// FileSource reads values from a flat YAML file of lower-cased names, e.g.
+//
+//	spotify_client_id: abc
+//	spotify_client_secret: def
+type FileSource struct {
+	Values map[string]string
+}
+
+// NewFileSource loads a FileSource from a YAML file.
+func NewFileSource(path string) (*FileSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]string)
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return &FileSource{
+		Values: values,
+	}, nil
+}
+
+func (f *FileSource) Get(ctx context.Context, name string) (string, error) {
+	value, ok := f.Values[strings.ToLower(name)]
+	if !ok || value == "" {
+		return "", fmt.Errorf("%s: %w", strings.ToLower(name), ErrNotSet)
+	}
+	return value, nil
+}
+
+// ChainSource returns the value from the first source that has one.
+type ChainSource []Source
+
+func (c ChainSource) Get(ctx context.Context, name string) (string, error) {
+	var errs []error
+	for _, source := range c {
+		value, err := source.Get(ctx, name)
+		if err == nil {
+			return value, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return "", fmt.Errorf("%s: %w", name, ErrNotSet)
+	}
+	return "", errors.Join(errs...)
+}
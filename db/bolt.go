@@ -0,0 +1,308 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore implements Store in a single local bbolt file, for development and offline CI.
+// Documents are stored as JSON in one bucket per collection, keyed by their ID.
+type BoltStore struct {
+	DB *bolt.DB
+}
+
+// NewBoltStore opens, or creates, the bbolt database at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	database, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database %s: %w", path, err)
+	}
+
+	err = database.Update(func(tx *bolt.Tx) error {
+		for _, collection := range []string{UserCollection, TrackCollection, TrackSamplesCollection, SessionCollection, GenerationCollection, JobCollection} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(collection)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to create bolt buckets: %w", err)
+	}
+
+	return &BoltStore{
+		DB: database,
+	}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.DB.Close()
+}
+
+// get decodes the document stored under key into v, returning ErrNotFound when it does not exist.
+func (s *BoltStore) get(collection, key string, v any) error {
+	return s.DB.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(collection)).Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, v)
+	})
+}
+
+// put stores v as JSON under key, replacing any existing document.
+func (s *BoltStore) put(collection, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(collection)).Put([]byte(key), data)
+	})
+}
+
+func (s *BoltStore) CreateUser(ctx context.Context, user User) (string, error) {
+	if err := s.put(UserCollection, user.ID, user); err != nil {
+		logger.LogError(ctx, "Error occurred at CreateUser: %v", err)
+		return "", fmt.Errorf("failed to store user with ID %s: %w", user.ID, err)
+	}
+
+	return user.ID, nil
+}
+
+func (s *BoltStore) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	var user User
+	if err := s.get(UserCollection, userID, &user); err != nil {
+		return nil, fmt.Errorf("user with ID %s: %w", userID, err)
+	}
+
+	return &user, nil
+}
+
+func (s *BoltStore) GetTracks(ctx context.Context, ID string) (*Tracks, error) {
+	var tracks Tracks
+	if err := s.get(TrackCollection, ID, &tracks); err != nil {
+		return nil, fmt.Errorf("track with ID %s: %w", ID, err)
+	}
+
+	if time.Now().After(tracks.TTL) {
+		return nil, fmt.Errorf("track with ID %s: %w", ID, ErrNotFound)
+	}
+
+	return &tracks, nil
+}
+
+func (s *BoltStore) SetTracks(ctx context.Context, ID string, tracks []string, provenance []TrackProvenance) error {
+	err := s.put(TrackCollection, ID, Tracks{
+		ID:         ID,
+		Tracks:     tracks,
+		Provenance: provenance,
+		TTL:        time.Now().Add(7 * 24 * time.Hour),
+	})
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at SetTracks: %v", err)
+		return fmt.Errorf("failed to store tracks: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltStore) GetTrackSamples(ctx context.Context, key string) (*TrackSamples, error) {
+	var trackSamples TrackSamples
+	if err := s.get(TrackSamplesCollection, key, &trackSamples); err != nil {
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get track samples: %w", err)
+	}
+
+	if time.Now().After(trackSamples.TTL) {
+		return nil, nil
+	}
+
+	return &trackSamples, nil
+}
+
+func (s *BoltStore) SetTrackSamples(ctx context.Context, key string, samples []TrackProvenance) error {
+	ttl := SamplesTTL
+	if len(samples) == 0 {
+		ttl = NoSamplesTTL
+	}
+
+	err := s.put(TrackSamplesCollection, key, TrackSamples{
+		Key:     key,
+		Samples: samples,
+		TTL:     time.Now().Add(ttl),
+	})
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at SetTrackSamples: %v", err)
+		return fmt.Errorf("failed to store track samples: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltStore) CreateSession(ctx context.Context, session Session) error {
+	if err := s.put(SessionCollection, session.ID, session); err != nil {
+		logger.LogError(ctx, "Error occurred at CreateSession: %v", err)
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltStore) GetSession(ctx context.Context, ID string) (*Session, error) {
+	var session Session
+	if err := s.get(SessionCollection, ID, &session); err != nil {
+		return nil, fmt.Errorf("session: %w", err)
+	}
+
+	if time.Now().After(session.Expiry) {
+		return nil, fmt.Errorf("session: %w", ErrNotFound)
+	}
+
+	return &session, nil
+}
+
+func (s *BoltStore) DeleteSession(ctx context.Context, ID string) error {
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(SessionCollection)).Delete([]byte(ID))
+	})
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at DeleteSession: %v", err)
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions deletes every session past its expiry and returns how many it deleted.
+func (s *BoltStore) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	now := time.Now()
+	deleted := 0
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(SessionCollection))
+
+		var expired [][]byte
+		err := bucket.ForEach(func(key, data []byte) error {
+			var session Session
+			if err := json.Unmarshal(data, &session); err != nil {
+				return err
+			}
+			if now.After(session.Expiry) {
+				expired = append(expired, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// keys can't be deleted while ForEach iterates
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		deleted = len(expired)
+		return nil
+	})
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at DeleteExpiredSessions: %v", err)
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Generations are keyed by user ID and generation ID so a user's history is a prefix scan.
+func generationKey(userID, ID string) []byte {
+	return []byte(userID + "/" + ID)
+}
+
+func (s *BoltStore) CreateGeneration(ctx context.Context, generation Generation) error {
+	data, err := json.Marshal(generation)
+	if err != nil {
+		return fmt.Errorf("failed to encode generation: %w", err)
+	}
+
+	err = s.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(GenerationCollection)).Put(generationKey(generation.UserID, generation.ID), data)
+	})
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at CreateGeneration: %v", err)
+		return fmt.Errorf("failed to store generation: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltStore) ListGenerations(ctx context.Context, userID string, limit int) ([]Generation, error) {
+	var generations []Generation
+	prefix := generationKey(userID, "")
+
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(GenerationCollection)).Cursor()
+		for key, data := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			var generation Generation
+			if err := json.Unmarshal(data, &generation); err != nil {
+				return err
+			}
+			generations = append(generations, generation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list generations: %w", err)
+	}
+
+	return sortGenerations(generations, limit), nil
+}
+
+func (s *BoltStore) SaveJob(ctx context.Context, job Job) error {
+	if err := s.put(JobCollection, job.ID, job); err != nil {
+		logger.LogError(ctx, "Error occurred at SaveJob: %v", err)
+		return fmt.Errorf("failed to store job: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltStore) GetJob(ctx context.Context, ID string) (*Job, error) {
+	var job Job
+	if err := s.get(JobCollection, ID, &job); err != nil {
+		return nil, fmt.Errorf("job: %w", err)
+	}
+
+	return &job, nil
+}
+
+func (s *BoltStore) UnfinishedJobs(ctx context.Context) ([]Job, error) {
+	var jobs []Job
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(JobCollection)).ForEach(func(_, data []byte) error {
+			var job Job
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if !job.Finished() {
+				jobs = append(jobs, job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at UnfinishedJobs: %v", err)
+		return nil, fmt.Errorf("failed to list unfinished jobs: %w", err)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,18 @@
+package db
+
+import "cloud.google.com/go/firestore"
+
+// FirestoreStore implements Store on top of Cloud Firestore.
+type FirestoreStore struct {
+	Client *firestore.Client
+}
+
+func NewFirestoreStore(client *firestore.Client) *FirestoreStore {
+	return &FirestoreStore{
+		Client: client,
+	}
+}
+
+func (s *FirestoreStore) Close() error {
+	return s.Client.Close()
+}
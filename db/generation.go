@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	"google.golang.org/api/iterator"
+)
+
+// Generation records a playlist generated for a user.
+type Generation struct {
+	ID          string `firestore:"id" json:"id"`
+	UserID      string `firestore:"user_id" json:"userId"`
+	AlbumID     string `firestore:"album_id" json:"albumId"`
+	AlbumName   string `firestore:"album_name" json:"albumName"`
+	PlaylistID  string `firestore:"playlist_id" json:"playlistId"`
+	PlaylistURL string `firestore:"playlist_url" json:"playlistUrl"`
+	// SnapshotID is the playlist's version once its tracks were written.
+	SnapshotID    string    `firestore:"snapshot_id" json:"snapshotId,omitempty"`
+	Tracks        int       `firestore:"tracks" json:"tracks"`
+	LowConfidence int       `firestore:"low_confidence" json:"lowConfidence"`
+	Reverse       bool      `firestore:"reverse" json:"reverse"`
+	CreatedAt     time.Time `firestore:"created_at" json:"createdAt"`
+	// SeedType is what the playlist was generated from, an album when empty. See Job.SeedType.
+	SeedType string `firestore:"seed_type" json:"seedType,omitempty"`
+	// OmittedReleases counts the releases of an artist seed that were left out.
+	OmittedReleases int `firestore:"omitted_releases" json:"omittedReleases,omitempty"`
+}
+
+const GenerationCollection = "Generations"
+
+// sortGenerations orders generations newest first and keeps at most limit of them.
+// A limit of zero keeps everything.
+func sortGenerations(generations []Generation, limit int) []Generation {
+	slices.SortFunc(generations, func(a, b Generation) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	if limit > 0 && len(generations) > limit {
+		generations = generations[:limit]
+	}
+
+	return generations
+}
+
+func (s *FirestoreStore) CreateGeneration(ctx context.Context, generation Generation) error {
+	_, err := s.Client.Collection(GenerationCollection).Doc(generation.ID).Set(ctx, generation)
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at CreateGeneration: %v", err)
+		return fmt.Errorf("failed to store generation: %w", err)
+	}
+
+	return nil
+}
+
+// ListGenerations returns the user's generations, newest first. Sorting happens here rather than
+// in the query so no composite index is needed.
+func (s *FirestoreStore) ListGenerations(ctx context.Context, userID string, limit int) ([]Generation, error) {
+	iter := s.Client.Collection(GenerationCollection).Where("user_id", "==", userID).Documents(ctx)
+	defer iter.Stop()
+
+	var generations []Generation
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			logger.LogError(ctx, "Error occurred at ListGenerations iter.Next(): %v", err)
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		var generation Generation
+		if err := doc.DataTo(&generation); err != nil {
+			return nil, fmt.Errorf("failed to map document data: %w", err)
+		}
+		generations = append(generations, generation)
+	}
+
+	return sortGenerations(generations, limit), nil
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// JobStatus is the state of a playlist generation job.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// Job tracks a playlist's generation while it runs in the background.
+type Job struct {
+	ID          string            `firestore:"id" json:"id"`
+	UserID      string            `firestore:"user_id" json:"userId"`
+	AlbumID     string            `firestore:"album_id" json:"albumId"`
+	AlbumName   string            `firestore:"album_name" json:"albumName,omitempty"`
+	Status      JobStatus         `firestore:"status" json:"status"`
+	TracksTotal int               `firestore:"tracks_total" json:"tracksTotal"`
+	TracksDone  int               `firestore:"tracks_done" json:"tracksDone"`
+	Error       string            `firestore:"error" json:"error,omitempty"`
+	PlaylistID  string            `firestore:"playlist_id" json:"playlistId,omitempty"`
+	PlaylistURL string            `firestore:"playlist_url" json:"playlistUrl,omitempty"`
+	Provenance  []TrackProvenance `firestore:"provenance" json:"provenance,omitempty"`
+	CreatedAt   time.Time         `firestore:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time         `firestore:"updated_at" json:"updatedAt"`
+	// SeedType is what the playlist is generated from, an album when empty. AlbumID and
+	// AlbumName hold the seed's ID and name whatever its type; they keep the names they had when
+	// albums were the only seed.
+	SeedType string `firestore:"seed_type" json:"seedType,omitempty"`
+	// OmittedReleases counts the releases of an artist seed that were left out.
+	OmittedReleases int `firestore:"omitted_releases" json:"omittedReleases,omitempty"`
+}
+
+const JobCollection = "Jobs"
+
+// Finished reports whether the job has stopped, successfully or not.
+func (j Job) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+func (s *FirestoreStore) SaveJob(ctx context.Context, job Job) error {
+	_, err := s.Client.Collection(JobCollection).Doc(job.ID).Set(ctx, job)
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at SaveJob: %v", err)
+		return fmt.Errorf("failed to store job: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FirestoreStore) GetJob(ctx context.Context, ID string) (*Job, error) {
+	doc, err := s.Client.Collection(JobCollection).Doc(ID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, fmt.Errorf("job %w", ErrNotFound)
+		}
+		logger.LogError(ctx, "Error occurred at GetJob: %v", err)
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	var job Job
+	if err := doc.DataTo(&job); err != nil {
+		return nil, fmt.Errorf("failed to map document data: %w", err)
+	}
+
+	return &job, nil
+}
+
+// UnfinishedJobs returns the jobs that are queued or running.
+func (s *FirestoreStore) UnfinishedJobs(ctx context.Context) ([]Job, error) {
+	iter := s.Client.Collection(JobCollection).Where("status", "in", []string{string(JobQueued), string(JobRunning)}).Documents(ctx)
+	defer iter.Stop()
+
+	var jobs []Job
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			logger.LogError(ctx, "Error occurred at UnfinishedJobs iter.Next(): %v", err)
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		var job Job
+		if err := doc.DataTo(&job); err != nil {
+			return nil, fmt.Errorf("failed to map document data: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
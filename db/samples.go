@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TrackSamples caches the samples found for a single track. A document with no samples
+// records that none were found.
+type TrackSamples struct {
+	Key     string            `firestore:"key"`
+	Samples []TrackProvenance `firestore:"samples"`
+	TTL     time.Time         `firestore:"ttl"`
+}
+
+const (
+	TrackSamplesCollection = "TrackSamples"
+	// SamplesTTL is how long found samples are cached.
+	SamplesTTL = 30 * 24 * time.Hour
+	// NoSamplesTTL is how long a track without samples is cached, kept short so new
+	// Genius entries are picked up.
+	NoSamplesTTL = 3 * 24 * time.Hour
+)
+
+// trackSamplesDocID derives a document ID from a cache key, which may contain characters
+// Firestore does not allow in IDs.
+func trackSamplesDocID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetTrackSamples returns the cached samples for a key, or nil when the key is not cached or has expired.
+func (s *FirestoreStore) GetTrackSamples(ctx context.Context, key string) (*TrackSamples, error) {
+	doc, err := s.Client.Collection(TrackSamplesCollection).Doc(trackSamplesDocID(key)).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		logger.LogError(ctx, "Error occurred at GetTrackSamples: %v", err)
+		return nil, fmt.Errorf("failed to get track samples: %w", err)
+	}
+
+	var trackSamples TrackSamples
+	if err := doc.DataTo(&trackSamples); err != nil {
+		logger.LogError(ctx, "Error occurred at GetTrackSamples doc.DataTo: %v", err)
+		return nil, fmt.Errorf("failed to map document data: %w", err)
+	}
+
+	// Firestore removes expired documents lazily
+	if time.Now().After(trackSamples.TTL) {
+		return nil, nil
+	}
+
+	return &trackSamples, nil
+}
+
+func (s *FirestoreStore) SetTrackSamples(ctx context.Context, key string, samples []TrackProvenance) error {
+	ttl := SamplesTTL
+	if len(samples) == 0 {
+		ttl = NoSamplesTTL
+	}
+
+	_, err := s.Client.Collection(TrackSamplesCollection).Doc(trackSamplesDocID(key)).Set(ctx, TrackSamples{
+		Key:     key,
+		Samples: samples,
+		TTL:     time.Now().Add(ttl),
+	})
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at SetTrackSamples: %v", err)
+		return fmt.Errorf("failed to store track samples: %w", err)
+	}
+
+	return nil
+}
+
+// SampleCache implements sampled.SampleCache on top of a Store.
+type SampleCache struct {
+	Store Store
+}
+
+func (c *SampleCache) GetSamples(ctx context.Context, key string) ([]*sampled.SpotifyTrack, bool, error) {
+	trackSamples, err := c.Store.GetTrackSamples(ctx, key)
+	if err != nil || trackSamples == nil {
+		return nil, false, err
+	}
+
+	samples := make([]*sampled.SpotifyTrack, len(trackSamples.Samples))
+	for i, entry := range trackSamples.Samples {
+		samples[i] = entry.Track()
+	}
+
+	return samples, true, nil
+}
+
+func (c *SampleCache) SetSamples(ctx context.Context, key string, samples []*sampled.SpotifyTrack) error {
+	entries := make([]TrackProvenance, len(samples))
+	for i, sample := range samples {
+		entries[i] = NewTrackProvenance(sample)
+	}
+
+	return c.Store.SetTrackSamples(ctx, key, entries)
+}
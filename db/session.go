@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Session ties an opaque session ID, sent to the browser as a cookie, to a stored user.
+type Session struct {
+	ID        string    `firestore:"id"`
+	UserID    string    `firestore:"user_id"`
+	CSRFToken string    `firestore:"csrf_token"`
+	Expiry    time.Time `firestore:"expiry"`
+	// Authenticated is set for sessions of users who signed in with Spotify, and the API tokens
+	// they issue. Visitors sharing the default account's session don't have it.
+	Authenticated bool `firestore:"authenticated"`
+}
+
+const (
+	SessionCollection = "Sessions"
+	// SessionTTL is how long a session lasts after login.
+	SessionTTL = 7 * 24 * time.Hour
+	// AnonymousSessionTTL is how long a visitor's session in the default account lasts.
+	AnonymousSessionTTL = 24 * time.Hour
+)
+
+func (s *FirestoreStore) CreateSession(ctx context.Context, session Session) error {
+	_, err := s.Client.Collection(SessionCollection).Doc(session.ID).Set(ctx, session)
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at CreateSession: %v", err)
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FirestoreStore) GetSession(ctx context.Context, ID string) (*Session, error) {
+	doc, err := s.Client.Collection(SessionCollection).Doc(ID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, fmt.Errorf("session %w", ErrNotFound)
+		}
+		logger.LogError(ctx, "Error occurred at GetSession: %v", err)
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	var session Session
+	if err := doc.DataTo(&session); err != nil {
+		return nil, fmt.Errorf("failed to map document data: %w", err)
+	}
+
+	if time.Now().After(session.Expiry) {
+		return nil, fmt.Errorf("session %w", ErrNotFound)
+	}
+
+	return &session, nil
+}
+
+func (s *FirestoreStore) DeleteSession(ctx context.Context, ID string) error {
+	_, err := s.Client.Collection(SessionCollection).Doc(ID).Delete(ctx)
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at DeleteSession: %v", err)
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions deletes every session past its expiry and returns how many it deleted.
+func (s *FirestoreStore) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	iter := s.Client.Collection(SessionCollection).Where("expiry", "<", time.Now()).Documents(ctx)
+	defer iter.Stop()
+
+	deleted := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			logger.LogError(ctx, "Error occurred at DeleteExpiredSessions iter.Next(): %v", err)
+			return deleted, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return deleted, fmt.Errorf("failed to delete session: %w", err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
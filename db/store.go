@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Store persists users, sessions, generation jobs, generated playlists and cached playlist data. FirestoreStore is used in production and
+// BoltStore keeps everything in a local file.
+type Store interface {
+	CreateUser(ctx context.Context, user User) (string, error)
+	GetUserByID(ctx context.Context, userID string) (*User, error)
+
+	GetTracks(ctx context.Context, ID string) (*Tracks, error)
+	SetTracks(ctx context.Context, ID string, tracks []string, provenance []TrackProvenance) error
+
+	GetTrackSamples(ctx context.Context, key string) (*TrackSamples, error)
+	SetTrackSamples(ctx context.Context, key string, samples []TrackProvenance) error
+
+	CreateSession(ctx context.Context, session Session) error
+	GetSession(ctx context.Context, ID string) (*Session, error)
+	DeleteSession(ctx context.Context, ID string) error
+	DeleteExpiredSessions(ctx context.Context) (int, error)
+
+	CreateGeneration(ctx context.Context, generation Generation) error
+	ListGenerations(ctx context.Context, userID string, limit int) ([]Generation, error)
+
+	SaveJob(ctx context.Context, job Job) error
+	GetJob(ctx context.Context, ID string) (*Job, error)
+	UnfinishedJobs(ctx context.Context) ([]Job, error)
+
+	Close() error
+}
+
+// Store backends selectable in configuration.
+const (
+	FirestoreBackend = "firestore"
+	BoltBackend      = "bolt"
+)
+
+// ErrNotFound is returned when a requested document does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ParseBackend validates a storage backend name.
+func ParseBackend(name string) (string, error) {
+	switch name {
+	case FirestoreBackend, BoltBackend:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown storage backend: %q", name)
+	}
+}
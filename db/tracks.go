@@ -5,21 +5,78 @@ import (
 	"fmt"
 	"time"
 
-	"cloud.google.com/go/firestore"
+	"github.com/ericflores108/spotify/genius"
 	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
 	"google.golang.org/api/iterator"
 )
 
 type Tracks struct {
-	ID     string    `firestore:"id"`
-	Tracks []string  `firestore:"tracks"`
-	TTL    time.Time `firestore:"ttl"`
+	ID         string            `firestore:"id"`
+	Tracks     []string          `firestore:"tracks"`
+	Provenance []TrackProvenance `firestore:"provenance"`
+	TTL        time.Time         `firestore:"ttl"`
+}
+
+// TrackProvenance records why a sample was added to a playlist and which source found it.
+type TrackProvenance struct {
+	URI          string   `firestore:"uri" json:"uri"`
+	Name         string   `firestore:"name" json:"name"`
+	Artist       string   `firestore:"artist" json:"artist"`
+	Sources      []string `firestore:"sources" json:"sources"`
+	Confidence   float64  `firestore:"confidence" json:"confidence"`
+	Relationship string   `firestore:"relationship" json:"relationship"`
+	GeniusSongID int      `firestore:"genius_song_id" json:"geniusSongId,omitempty"`
+	GeniusURL    string   `firestore:"genius_url" json:"geniusUrl,omitempty"`
+	AIModel      string   `firestore:"ai_model" json:"aiModel,omitempty"`
+	Verified     bool     `firestore:"verified" json:"verified"`
+	OriginURI    string   `firestore:"origin_uri" json:"originUri,omitempty"`
+	OriginName   string   `firestore:"origin_name" json:"originName,omitempty"`
+	OriginArtist string   `firestore:"origin_artist" json:"originArtist,omitempty"`
 }
 
 const TrackCollection = "SpotifyTracks"
 
-func GetTracks(ctx context.Context, client *firestore.Client, ID string) ([]string, error) {
-	query := client.Collection(TrackCollection).Where("id", "==", ID).Limit(1)
+// NewTrackProvenance records the provenance of a resolved sample.
+func NewTrackProvenance(sample *sampled.SpotifyTrack) TrackProvenance {
+	entry := TrackProvenance{
+		URI:          sample.URI,
+		Name:         sample.Name,
+		Artist:       sample.Artist,
+		Sources:      sample.Sources,
+		Confidence:   sample.Confidence,
+		Relationship: string(sample.Relationship),
+		GeniusSongID: sample.GeniusID,
+		GeniusURL:    sample.GeniusURL,
+		AIModel:      sample.AIModel,
+		Verified:     sample.Verified,
+	}
+	if sample.Origin != nil {
+		entry.OriginURI = sample.Origin.URI
+		entry.OriginName = sample.Origin.Name
+		entry.OriginArtist = sample.Origin.Artist
+	}
+	return entry
+}
+
+// Track converts the provenance back into a sample. The origin is not restored.
+func (p TrackProvenance) Track() *sampled.SpotifyTrack {
+	return &sampled.SpotifyTrack{
+		Name:         p.Name,
+		Artist:       p.Artist,
+		URI:          p.URI,
+		Relationship: genius.RelationshipType(p.Relationship),
+		GeniusID:     p.GeniusSongID,
+		Confidence:   p.Confidence,
+		Sources:      p.Sources,
+		GeniusURL:    p.GeniusURL,
+		AIModel:      p.AIModel,
+		Verified:     p.Verified,
+	}
+}
+
+func (s *FirestoreStore) GetTracks(ctx context.Context, ID string) (*Tracks, error) {
+	query := s.Client.Collection(TrackCollection).Where("id", "==", ID).Limit(1)
 
 	iter := query.Documents(ctx)
 	defer iter.Stop()
@@ -27,29 +84,30 @@ func GetTracks(ctx context.Context, client *firestore.Client, ID string) ([]stri
 	doc, err := iter.Next()
 	if err != nil {
 		if err == iterator.Done {
-			return nil, fmt.Errorf("track with ID %s not found", ID)
+			return nil, fmt.Errorf("track with ID %s %w", ID, ErrNotFound)
 		}
-		logger.LogError("Error occurred at GetTracks iter.Next(): %v", err)
+		logger.LogError(ctx, "Error occurred at GetTracks iter.Next(): %v", err)
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
 	var tracks Tracks
 	if err := doc.DataTo(&tracks); err != nil {
-		logger.LogError("Error occurred at GetTracks doc.DataTo: %v", err)
+		logger.LogError(ctx, "Error occurred at GetTracks doc.DataTo: %v", err)
 		return nil, fmt.Errorf("failed to map document data: %w", err)
 	}
 
-	return tracks.Tracks, nil
+	return &tracks, nil
 }
 
-func SetTracks(ctx context.Context, client *firestore.Client, ID string, tracks []string) error {
-	_, _, err := client.Collection(TrackCollection).Add(ctx, Tracks{
-		ID:     ID,
-		Tracks: tracks,
-		TTL:    time.Now().Add(7 * 24 * time.Hour),
+func (s *FirestoreStore) SetTracks(ctx context.Context, ID string, tracks []string, provenance []TrackProvenance) error {
+	_, _, err := s.Client.Collection(TrackCollection).Add(ctx, Tracks{
+		ID:         ID,
+		Tracks:     tracks,
+		Provenance: provenance,
+		TTL:        time.Now().Add(7 * 24 * time.Hour),
 	})
 	if err != nil {
-		logger.LogError("Error occurred at SetAlbumTracks: %v", err)
+		logger.LogError(ctx, "Error occurred at SetAlbumTracks: %v", err)
 		return fmt.Errorf("failed to store tracks: %w", err)
 	}
 
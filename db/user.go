@@ -3,25 +3,28 @@ package db
 import (
 	"context"
 	"fmt"
+	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/ericflores108/spotify/logger"
 	"google.golang.org/api/iterator"
 )
 
 // User represents a user document in Firestore
 type User struct {
-	ID           string `firestore:"id"`
-	DisplayName  string `firestore:"display_name"`
-	AccessToken  string `firestore:"access_token"`
-	RefreshToken string `firestore:"refresh_token"`
+	ID           string    `firestore:"id"`
+	DisplayName  string    `firestore:"display_name"`
+	AccessToken  string    `firestore:"access_token"`
+	RefreshToken string    `firestore:"refresh_token"`
+	TokenExpiry  time.Time `firestore:"token_expiry"`
+	// Country is the user's Spotify market, used to only pick tracks they can play.
+	Country string `firestore:"country"`
 }
 
 const UserCollection = "SpotifyUser"
 
-func CreateUser(ctx context.Context, client *firestore.Client, user User) (string, error) {
+func (s *FirestoreStore) CreateUser(ctx context.Context, user User) (string, error) {
 	// Check if the user already exists
-	query := client.Collection(UserCollection).Where("id", "==", user.ID).Limit(1)
+	query := s.Client.Collection(UserCollection).Where("id", "==", user.ID).Limit(1)
 	iter := query.Documents(ctx)
 	defer iter.Stop()
 
@@ -30,28 +33,28 @@ func CreateUser(ctx context.Context, client *firestore.Client, user User) (strin
 		// If the user exists, update the document
 		_, err := docSnap.Ref.Set(ctx, user)
 		if err != nil {
-			logger.LogError("Error occurred at CreateUser: %v", err)
+			logger.LogError(ctx, "Error occurred at CreateUser: %v", err)
 			return "", fmt.Errorf("failed to update user with ID %s: %w", user.ID, err)
 		}
 		return docSnap.Ref.ID, nil
 	} else if err != iterator.Done {
 		// If there's an error other than no documents, return the error
-		logger.LogError("Error occurred at CreateUser iterator: %v", err)
+		logger.LogError(ctx, "Error occurred at CreateUser iterator: %v", err)
 		return "", fmt.Errorf("failed to query user: %w", err)
 	}
 
 	// If the user does not exist, create a new document
-	docRef, _, err := client.Collection(UserCollection).Add(ctx, user)
+	docRef, _, err := s.Client.Collection(UserCollection).Add(ctx, user)
 	if err != nil {
-		logger.LogError("Error occurred. failed to create user: %v", err)
+		logger.LogError(ctx, "Error occurred. failed to create user: %v", err)
 		return "", fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return docRef.ID, nil
 }
 
-func GetUserByID(ctx context.Context, client *firestore.Client, userID string) (string, error) {
-	query := client.Collection("SpotifyUser").Where("id", "==", userID).Limit(1)
+func (s *FirestoreStore) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	query := s.Client.Collection(UserCollection).Where("id", "==", userID).Limit(1)
 
 	iter := query.Documents(ctx)
 	defer iter.Stop()
@@ -59,15 +62,15 @@ func GetUserByID(ctx context.Context, client *firestore.Client, userID string) (
 	doc, err := iter.Next()
 	if err != nil {
 		if err == iterator.Done {
-			return "", fmt.Errorf("user with ID %s not found", userID)
+			return nil, fmt.Errorf("user with ID %s %w", userID, ErrNotFound)
 		}
-		return "", fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
 	var user User
 	if err := doc.DataTo(&user); err != nil {
-		return "", fmt.Errorf("failed to map document data: %w", err)
+		return nil, fmt.Errorf("failed to map document data: %w", err)
 	}
 
-	return user.AccessToken, nil
+	return &user, nil
 }
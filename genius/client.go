@@ -1,40 +1,149 @@
 package genius
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/match"
+	"github.com/ericflores108/spotify/ratelimit"
+)
+
+const (
+	// DefaultBaseURL is the Genius API. Clients with another BaseURL, e.g. a local stand-in
+	// server, send every request there instead.
+	DefaultBaseURL = "https://api.genius.com"
+	// DefaultRequestsPerSecond keeps well under the limits Genius enforces without publishing.
+	DefaultRequestsPerSecond = 5
+	// maxRetries is how many times a throttled or failed request is retried.
+	maxRetries = 3
+	// baseBackoff is the wait before the first retry of a failure. It doubles with each retry.
+	baseBackoff = 500 * time.Millisecond
+	// maxBackoff caps the wait between retries of a failure.
+	maxBackoff = 10 * time.Second
+	// maxRetryAfter is the longest Retry-After that is waited out. Longer bans fail the request.
+	maxRetryAfter = time.Minute
+	// maxErrorBody is how much of an error response is quoted in the returned error.
+	maxErrorBody = 512
+)
+
+// limiter paces requests from every GeniusClient, since Genius rate limits the app as a whole.
+var limiter = ratelimit.NewLimiter(DefaultRequestsPerSecond)
+
+// metrics counts requests to the Genius API, how often they were throttled, failed or were
+// retried, and how often the token was renewed. They are served at /debug/vars when running
+// locally.
+var metrics = expvar.NewMap("genius")
+
+const (
+	metricRequests     = "requests"
+	metricThrottled    = "throttled"
+	metricFailures     = "failures"
+	metricRetries      = "retries"
+	metricReauthorized = "reauthorized"
+	metricWaitMillis   = "waitMilliseconds"
 )
 
+// SetRateLimit changes how many requests per second all clients may send to the Genius API.
+func SetRateLimit(requestsPerSecond float64) {
+	limiter.SetRate(requestsPerSecond)
+}
+
+// GeniusClient calls the Genius API. Transient failures and rate limits are retried, and when
+// ClientID and ClientSecret are set a rejected token is replaced with a new client-credentials
+// token. It is safe for concurrent use.
 type GeniusClient struct {
 	Client      *http.Client
 	AccessToken string
+	// BaseURL is where requests are sent, DefaultBaseURL when empty.
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+
+	mu sync.Mutex
+}
+
+// NewClient generates a new GeniusClient by authenticating with the Genius API at baseURL, or
+// DefaultBaseURL when baseURL is empty.
+func NewClient(ctx context.Context, baseURL, clientID, clientSecret string) (*GeniusClient, error) {
+	g := &GeniusClient{
+		Client:       &http.Client{},
+		BaseURL:      baseURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}
+
+	if _, err := g.refresh(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *GeniusClient) baseURL() string {
+	if g.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(g.BaseURL, "/")
+}
+
+func (g *GeniusClient) canAuthenticate() bool {
+	return g.ClientID != "" && g.ClientSecret != ""
+}
+
+// token returns the access token to send, fetching one first if the client has none yet.
+func (g *GeniusClient) token(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	token := g.AccessToken
+	g.mu.Unlock()
+
+	if token == "" && g.canAuthenticate() {
+		return g.refresh(ctx, "")
+	}
+	return token, nil
 }
 
-// NewClient generates a new GeniusClient by authenticating with the Genius API.
-func NewClient(clientID, clientSecret string) (*GeniusClient, error) {
-	tokenURL := "https://api.genius.com/oauth/token"
+// refresh fetches a new client-credentials token to replace stale. If another caller already
+// replaced stale, the current token is returned without fetching again.
+func (g *GeniusClient) refresh(ctx context.Context, stale string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.AccessToken != stale {
+		return g.AccessToken, nil
+	}
 
 	// Prepare form data
 	form := url.Values{}
-	form.Set("client_id", clientID)
-	form.Set("client_secret", clientSecret)
+	form.Set("client_id", g.ClientID)
+	form.Set("client_secret", g.ClientSecret)
 	form.Set("grant_type", "client_credentials")
 
-	// Make the POST request
-	resp, err := http.PostForm(tokenURL, form)
+	resp, err := g.sendWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", g.baseURL()+"/oauth/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error making POST request: %w", err)
+		return "", fmt.Errorf("error making POST request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check for unsuccessful status
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get access token, status: %s", resp.Status)
+		return "", fmt.Errorf("failed to get access token, status: %s", resp.Status)
 	}
 
 	// Parse the JSON response
@@ -42,92 +151,159 @@ func NewClient(clientID, clientSecret string) (*GeniusClient, error) {
 		AccessToken string `json:"access_token"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
-		return nil, fmt.Errorf("error decoding JSON response: %w", err)
+		return "", fmt.Errorf("error decoding JSON response: %w", err)
 	}
 
 	// Check if the token was successfully retrieved
 	if responseData.AccessToken == "" {
-		return nil, errors.New("access token not found in response")
+		return "", errors.New("access token not found in response")
 	}
 
-	// Return the GeniusClient
-	return &GeniusClient{
-		Client:      &http.Client{},
-		AccessToken: responseData.AccessToken,
-	}, nil
+	if stale != "" {
+		metrics.Add(metricReauthorized, 1)
+	}
+	g.AccessToken = responseData.AccessToken
+	return g.AccessToken, nil
 }
 
-// Search searches for a track by title and artist.
-func (g *GeniusClient) Search(track, artist string) (*SearchResponse, error) {
-	baseURL := "https://api.genius.com/search"
-	params := url.Values{}
-
-	// Find the index of the parenthesis
-	if idx := strings.Index(track, "("); idx != -1 {
-		// Slice the string to exclude everything from the parenthesis onward
-		track = strings.TrimSpace(track[:idx])
+// get sends an authenticated GET to the API path and decodes the JSON response into out. When
+// Genius rejects the token, a new one is fetched and the request is sent once more.
+func (g *GeniusClient) get(ctx context.Context, path string, query url.Values, out any) error {
+	token, err := g.token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
 	}
 
-	params.Add("q", fmt.Sprintf("%s %s", track, artist))
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s?%s", baseURL, params.Encode()), nil)
+	resp, err := g.getWithToken(ctx, path, query, token)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", g.AccessToken))
+	if resp.StatusCode == http.StatusUnauthorized && g.canAuthenticate() {
+		resp.Body.Close()
 
-	resp, err := g.Client.Do(req)
-	if err != nil {
-		return nil, err
+		token, err = g.refresh(ctx, token)
+		if err != nil {
+			return fmt.Errorf("failed to renew access token: %w", err)
+		}
+
+		resp, err = g.getWithToken(ctx, path, query, token)
+		if err != nil {
+			return err
+		}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("request to %s failed with status %s: %s", path, resp.Status, body)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
 	}
 
-	var searchResponse SearchResponse
-	if err := json.Unmarshal(body, &searchResponse); err != nil {
-		return nil, err
+	return nil
+}
+
+func (g *GeniusClient) getWithToken(ctx context.Context, path string, query url.Values, token string) (*http.Response, error) {
+	endpoint := g.baseURL() + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
 	}
 
-	return &searchResponse, nil
+	return g.sendWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		return req, nil
+	})
 }
 
-// Songs retrieves detailed information about a song by its ID.
-func (g *GeniusClient) Songs(id string) (*SongResponse, error) {
-	baseURL := fmt.Sprintf("https://api.genius.com/songs/%s", id)
+// sendWithRetry sends the request built by newRequest once the shared rate limiter allows it.
+// A 429 pauses every client for the response's Retry-After and is then retried. Network errors
+// and 5xx responses are retried with backoff. When retries run out, or waiting would outlast
+// ctx, the last response or error is returned.
+func (g *GeniusClient) sendWithRetry(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("failed waiting to send request: %w", err)
+		}
+		metrics.Add(metricWaitMillis, time.Since(start).Milliseconds())
 
-	req, err := http.NewRequest("GET", baseURL, nil)
-	if err != nil {
-		return nil, err
-	}
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", g.AccessToken))
+		metrics.Add(metricRequests, 1)
+		resp, err := g.Client.Do(req)
 
-	resp, err := g.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+		var (
+			wait   time.Duration
+			reason string
+		)
+		switch {
+		case err != nil:
+			// a cancelled request is not worth retrying
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			metrics.Add(metricFailures, 1)
+			wait = ratelimit.Backoff(attempt, baseBackoff, maxBackoff)
+			reason = err.Error()
+		case resp.StatusCode == http.StatusTooManyRequests:
+			metrics.Add(metricThrottled, 1)
+			wait = ratelimit.RetryAfter(resp.Header, ratelimit.Backoff(attempt, baseBackoff, maxBackoff))
+			limiter.Pause(wait)
+			reason = resp.Status
+		case resp.StatusCode >= 500:
+			metrics.Add(metricFailures, 1)
+			wait = ratelimit.Backoff(attempt, baseBackoff, maxBackoff)
+			reason = resp.Status
+		default:
+			return resp, nil
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		if attempt == maxRetries || !ratelimit.CanWait(ctx, wait, maxRetryAfter) {
+			return resp, err
+		}
+
+		logger.LogInfo(ctx, "Genius request to %s failed (%s), retrying in %s", req.URL.Path, reason, wait)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		metrics.Add(metricRetries, 1)
+		metrics.Add(metricWaitMillis, wait.Milliseconds())
+
+		if err := ratelimit.Sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("failed waiting to retry request: %w", err)
+		}
 	}
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+// Search searches for a track by title and artist. Version suffixes such as "- Remastered" are
+// left out of the query, see match.CleanTitle. Use BestMatch to pick the track from the hits.
+func (g *GeniusClient) Search(ctx context.Context, track, artist string) (*SearchResponse, error) {
+	params := url.Values{}
+	params.Add("q", fmt.Sprintf("%s %s", match.CleanTitle(track), artist))
+
+	var searchResponse SearchResponse
+	if err := g.get(ctx, "/search", params, &searchResponse); err != nil {
 		return nil, err
 	}
 
+	return &searchResponse, nil
+}
+
+// Songs retrieves detailed information about a song by its ID.
+func (g *GeniusClient) Songs(ctx context.Context, id string) (*SongResponse, error) {
 	var songResponse SongResponse
-	if err := json.Unmarshal(body, &songResponse); err != nil {
+	if err := g.get(ctx, "/songs/"+url.PathEscape(id), nil, &songResponse); err != nil {
 		return nil, err
 	}
 
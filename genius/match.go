@@ -0,0 +1,49 @@
+package genius
+
+import (
+	"strings"
+
+	"github.com/ericflores108/spotify/match"
+)
+
+// isTranslation reports whether the hit is one of Genius's translated or romanized lyrics
+// pages. These are credited to Genius's own accounts, such as "Genius English Translations",
+// rather than the song's artist, and romanized pages end their title in "(Romanized)". Songs
+// that merely have "translation" in their title are kept.
+func isTranslation(result HitResult) bool {
+	artist := strings.ToLower(result.PrimaryArtist.Name)
+	title := strings.ToLower(strings.TrimSpace(result.Title))
+	return strings.HasPrefix(artist, "genius ") || strings.HasSuffix(title, "(romanized)")
+}
+
+// Score rates from 0 to 1 how well the hit matches the track and artist, comparing normalized
+// titles and the hit's primary artist. An artist only credited as featured on the hit scores
+// lower than the primary artist. Translations and hits that aren't songs score 0.
+func Score(hit Hit, track, artist string) float64 {
+	if hit.Type != "" && hit.Type != "song" {
+		return 0
+	}
+	if isTranslation(hit.Result) {
+		return 0
+	}
+
+	title := hit.Result.Title
+	if title == "" {
+		title = hit.Result.FullTitle
+	}
+
+	return match.Score(title, []string{hit.Result.PrimaryArtist.Name, hit.Result.ArtistNames}, track, artist)
+}
+
+// BestMatch returns the hit that best matches the track and artist, and its score. Of equally
+// good hits, the one Genius ranked first wins. ok is false when no hit scores at least
+// match.Threshold.
+func (r *SearchResponse) BestMatch(track, artist string) (best Hit, score float64, ok bool) {
+	for _, hit := range r.Response.Hits {
+		if s := Score(hit, track, artist); s > score {
+			best, score = hit, s
+		}
+	}
+
+	return best, score, score >= match.Threshold
+}
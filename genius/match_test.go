@@ -0,0 +1,105 @@
+package genius
+
+import "testing"
+
+func testHit(title, artist string) Hit {
+	hit := Hit{Type: "song", Result: HitResult{Title: title, ArtistNames: artist}}
+	hit.Result.PrimaryArtist.Name = artist
+	return hit
+}
+
+func TestIsTranslation(t *testing.T) {
+	tests := []struct {
+		title  string
+		artist string
+		want   bool
+	}{
+		{"Dynamite (English Translation)", "Genius English Translations", true},
+		{"Gangnam Style (강남스타일) (Romanized)", "Genius Romanizations", true},
+		{"Dynamite (Romanized)", "BTS", true},
+		{"Lost in Translation", "The Streets", false},
+		{"Translation", "Bad Religion", false},
+		{"Romanized Love Song", "Someone", false},
+		{"Dynamite", "BTS", false},
+		{"Geniuses", "Genius/GZA", false},
+	}
+
+	for _, test := range tests {
+		if got := isTranslation(testHit(test.title, test.artist).Result); got != test.want {
+			t.Errorf("isTranslation(%q by %q) = %v, want %v", test.title, test.artist, got, test.want)
+		}
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		hits   []Hit
+		track  string
+		artist string
+		want   int
+		ok     bool
+	}{
+		{
+			name:   "skips translations",
+			hits:   []Hit{testHit("Dynamite (Traducción al Español)", "Genius Traducciones al Español"), testHit("Dynamite", "BTS")},
+			track:  "Dynamite",
+			artist: "BTS",
+			want:   1,
+			ok:     true,
+		},
+		{
+			name:   "keeps songs named translation",
+			hits:   []Hit{testHit("Lost in Translation", "The Streets")},
+			track:  "Lost in Translation",
+			artist: "The Streets",
+			want:   0,
+			ok:     true,
+		},
+		{
+			name:   "matches without version suffixes",
+			hits:   []Hit{testHit("Heroes", "David Bowie")},
+			track:  "Heroes - 2017 Remaster",
+			artist: "David Bowie",
+			want:   0,
+			ok:     true,
+		},
+		{
+			name:   "prefers the searched artist",
+			hits:   []Hit{testHit("Hurt", "Johnny Cash"), testHit("Hurt", "Nine Inch Nails")},
+			track:  "Hurt",
+			artist: "Nine Inch Nails",
+			want:   1,
+			ok:     true,
+		},
+		{
+			name:   "skips hits that aren't songs",
+			hits:   []Hit{{Type: "album", Result: testHit("Hurt", "Nine Inch Nails").Result}},
+			track:  "Hurt",
+			artist: "Nine Inch Nails",
+			want:   -1,
+		},
+		{
+			name:   "no good hit",
+			hits:   []Hit{testHit("Hello", "Adele")},
+			track:  "Hell",
+			artist: "Nas",
+			want:   -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var response SearchResponse
+			response.Response.Hits = test.hits
+
+			best, _, ok := response.BestMatch(test.track, test.artist)
+			if ok != test.ok {
+				t.Fatalf("BestMatch(%q, %q) ok = %v, want %v", test.track, test.artist, ok, test.ok)
+			}
+			if ok && best != test.hits[test.want] {
+				t.Errorf("BestMatch(%q, %q) = %+v, want hit %d", test.track, test.artist, best.Result, test.want)
+			}
+		})
+	}
+}
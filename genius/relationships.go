@@ -0,0 +1,66 @@
+package genius
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RelationshipType is the kind of link Genius records between two songs in song_relationships.
+type RelationshipType string
+
+const (
+	Samples       RelationshipType = "samples"
+	Interpolates  RelationshipType = "interpolates"
+	CoverOf       RelationshipType = "cover_of"
+	RemixOf       RelationshipType = "remix_of"
+	TranslationOf RelationshipType = "translation_of"
+	LiveVersionOf RelationshipType = "live_version_of"
+
+	SampledIn       RelationshipType = "sampled_in"
+	InterpolatedBy  RelationshipType = "interpolated_by"
+	CoveredBy       RelationshipType = "covered_by"
+	RemixedBy       RelationshipType = "remixed_by"
+	Translations    RelationshipType = "translations"
+	PerformedLiveAs RelationshipType = "performed_live_as"
+)
+
+// BorrowedRelationships lists every relationship in which a song borrows from an earlier one.
+var BorrowedRelationships = []RelationshipType{
+	Samples,
+	Interpolates,
+	CoverOf,
+	RemixOf,
+	TranslationOf,
+	LiveVersionOf,
+}
+
+// LegacyRelationships lists every relationship in which a song is borrowed by a later one.
+var LegacyRelationships = []RelationshipType{
+	SampledIn,
+	InterpolatedBy,
+	CoveredBy,
+	RemixedBy,
+	Translations,
+	PerformedLiveAs,
+}
+
+// Inverse returns the relationship seen from the other song, e.g. sampled_in for samples.
+func (r RelationshipType) Inverse() RelationshipType {
+	if i := slices.Index(BorrowedRelationships, r); i != -1 {
+		return LegacyRelationships[i]
+	}
+	if i := slices.Index(LegacyRelationships, r); i != -1 {
+		return BorrowedRelationships[i]
+	}
+	return r
+}
+
+// ParseRelationshipType converts a relationship type string, as used by Genius, into a RelationshipType.
+func ParseRelationshipType(s string) (RelationshipType, error) {
+	relationship := RelationshipType(strings.ToLower(strings.TrimSpace(s)))
+	if slices.Contains(BorrowedRelationships, relationship) || slices.Contains(LegacyRelationships, relationship) {
+		return relationship, nil
+	}
+	return "", fmt.Errorf("unknown relationship type: %q", s)
+}
@@ -2,16 +2,29 @@ package genius
 
 type SearchResponse struct {
 	Response struct {
-		Hits []struct {
-			Result struct {
-				ID        int    `json:"id"`
-				FullTitle string `json:"full_title"`
-				APIPath   string `json:"api_path"`
-			} `json:"result"`
-		} `json:"hits"`
+		Hits []Hit `json:"hits"`
 	} `json:"response"`
 }
 
+// Hit is one search result. Only hits of type "song" are songs.
+type Hit struct {
+	Type   string    `json:"type"`
+	Result HitResult `json:"result"`
+}
+
+type HitResult struct {
+	ID            int    `json:"id"`
+	Title         string `json:"title"`
+	FullTitle     string `json:"full_title"`
+	APIPath       string `json:"api_path"`
+	URL           string `json:"url"`
+	ArtistNames   string `json:"artist_names"`
+	PrimaryArtist struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"primary_artist"`
+}
+
 type SongResponse struct {
 	Response struct {
 		Song struct {
@@ -19,9 +32,11 @@ type SongResponse struct {
 			Title             string `json:"title"`
 			URL               string `json:"url"`
 			SongRelationships []struct {
-				RelationshipType string `json:"type"`
+				RelationshipType RelationshipType `json:"type"`
 				Songs            []struct {
+					ID     int    `json:"id"`
 					Title  string `json:"title"`
+					URL    string `json:"url"`
 					Artist string `json:"primary_artist_names"`
 				} `json:"songs"`
 			} `json:"song_relationships"`
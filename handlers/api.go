@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/genius"
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+// Error codes returned in API error bodies.
+const (
+	CodeBadRequest    = "bad_request"
+	CodeUnauthorized  = "unauthorized"
+	CodeForbidden     = "forbidden"
+	CodeNotFound      = "not_found"
+	CodeUpstreamError = "upstream_error"
+	CodeUnavailable   = "unavailable"
+	CodeInternalError = "internal_error"
+)
+
+// maxGenerations caps how many past generations a single API request returns.
+const maxGenerations = 100
+
+// APIError is the body of every failed API response.
+type APIError struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+type APIErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteJSON writes v as the JSON response body with the given status.
+func WriteJSON(w http.ResponseWriter, ctx context.Context, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.LogError(ctx, "Failed to encode JSON response: %v", err)
+	}
+}
+
+// WriteAPIError writes a structured API error.
+func WriteAPIError(w http.ResponseWriter, ctx context.Context, status int, code, message string) {
+	WriteJSON(w, ctx, status, APIError{
+		Error: APIErrorDetail{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+// writePipelineError maps playlist pipeline errors to API errors. Anything that is not an
+// unsupported seed, missing seed or empty result came from an upstream service.
+func writePipelineError(w http.ResponseWriter, ctx context.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUnsupportedSeed):
+		WriteAPIError(w, ctx, http.StatusBadRequest, CodeBadRequest, err.Error())
+	case errors.Is(err, ErrSeedNotFound), errors.Is(err, ErrNoTracks):
+		WriteAPIError(w, ctx, http.StatusNotFound, CodeNotFound, err.Error())
+	default:
+		WriteAPIError(w, ctx, http.StatusBadGateway, CodeUpstreamError, err.Error())
+	}
+}
+
+// provenanceList keeps empty results encoded as [] rather than null.
+func provenanceList(provenance []db.TrackProvenance) []db.TrackProvenance {
+	if provenance == nil {
+		return []db.TrackProvenance{}
+	}
+	return provenance
+}
+
+type samplesResponse struct {
+	Song    string               `json:"song"`
+	Artist  string               `json:"artist"`
+	Samples []db.TrackProvenance `json:"samples"`
+	// Incomplete is set when some samples failed to load and are missing.
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// SamplesAPIHandler looks up the samples of a single song.
+func (s *Service) SamplesAPIHandler(w http.ResponseWriter, ctx context.Context, song, artist string, relationships []genius.RelationshipType) {
+	samples, err := s.SampledManager.GetSamples(ctx, song, artist, relationships...)
+	if err != nil {
+		logger.LogError(ctx, "Failed to get samples for %s by %s: %v", song, artist, err)
+		if !errors.Is(err, sampled.ErrIncomplete) {
+			WriteAPIError(w, ctx, http.StatusBadGateway, CodeUpstreamError, "failed to look up samples")
+			return
+		}
+	}
+
+	response := samplesResponse{
+		Song:       song,
+		Artist:     artist,
+		Samples:    []db.TrackProvenance{},
+		Incomplete: err != nil,
+	}
+	for _, sample := range samples {
+		response.Samples = append(response.Samples, db.NewTrackProvenance(sample))
+	}
+
+	WriteJSON(w, ctx, http.StatusOK, response)
+}
+
+// seedSamplesResponse names the seed like db.Job does: albumId and albumName hold the ID and
+// name of any seed.
+type seedSamplesResponse struct {
+	SeedType  string               `json:"seedType"`
+	AlbumID   string               `json:"albumId"`
+	AlbumName string               `json:"albumName"`
+	Tracks    []string             `json:"tracks"`
+	Samples   []db.TrackProvenance `json:"samples"`
+	// OmittedReleases counts the releases of an artist seed that were left out.
+	OmittedReleases int `json:"omittedReleases,omitempty"`
+}
+
+// SeedSamplesAPIHandler previews the tracks a playlist for the seed would contain, without
+// creating it.
+func (s *Service) SeedSamplesAPIHandler(w http.ResponseWriter, ctx context.Context, session *db.Session, seed spotify.Resource, options sampled.CrawlOptions) {
+	user, spotifyClient, err := s.userClient(ctx, session.UserID)
+	if err != nil {
+		logger.LogError(ctx, "Failed to get session user: %v", err)
+		WriteAPIError(w, ctx, http.StatusUnauthorized, CodeUnauthorized, "failed to get user, please log in again")
+		return
+	}
+	ctx = spotify.WithMarket(ctx, user.Country)
+
+	samples, err := s.seedSamples(ctx, spotifyClient, seed, options, nil)
+	if err != nil {
+		logger.LogError(ctx, "Failed to generate tracks for %s: %v", seed.URI(), err)
+		writePipelineError(w, ctx, err)
+		return
+	}
+
+	WriteJSON(w, ctx, http.StatusOK, seedSamplesResponse{
+		SeedType:        string(samples.Seed.Type),
+		AlbumID:         samples.Seed.ID,
+		AlbumName:       samples.Name,
+		Tracks:          samples.Tracks,
+		Samples:         provenanceList(samples.Provenance),
+		OmittedReleases: samples.OmittedReleases,
+	})
+}
+
+type playlistResponse struct {
+	Generation db.Generation        `json:"generation"`
+	Samples    []db.TrackProvenance `json:"samples"`
+}
+
+// CreatePlaylistAPIHandler creates a playlist for the seed in the session user's account.
+func (s *Service) CreatePlaylistAPIHandler(w http.ResponseWriter, ctx context.Context, session *db.Session, seed spotify.Resource, options sampled.CrawlOptions) {
+	user, spotifyClient, err := s.userClient(ctx, session.UserID)
+	if err != nil {
+		logger.LogError(ctx, "Failed to get session user: %v", err)
+		WriteAPIError(w, ctx, http.StatusUnauthorized, CodeUnauthorized, "failed to get user, please log in again")
+		return
+	}
+	ctx = spotify.WithMarket(ctx, user.Country)
+
+	samples, err := s.seedSamples(ctx, spotifyClient, seed, options, nil)
+	if err != nil {
+		logger.LogError(ctx, "Failed to generate tracks for %s: %v", seed.URI(), err)
+		writePipelineError(w, ctx, err)
+		return
+	}
+
+	generation, err := s.createPlaylist(ctx, spotifyClient, user.ID, samples, options)
+	if err != nil {
+		logger.LogError(ctx, "Failed to create playlist: %v", err)
+		writePipelineError(w, ctx, err)
+		return
+	}
+
+	WriteJSON(w, ctx, http.StatusCreated, playlistResponse{
+		Generation: *generation,
+		Samples:    provenanceList(samples.Provenance),
+	})
+}
+
+type generationsResponse struct {
+	Generations []db.Generation `json:"generations"`
+}
+
+// GenerationsAPIHandler lists the session user's past generations, newest first.
+func (s *Service) GenerationsAPIHandler(w http.ResponseWriter, ctx context.Context, session *db.Session, limit int) {
+	if limit <= 0 || limit > maxGenerations {
+		limit = maxGenerations
+	}
+
+	generations, err := s.Store.ListGenerations(ctx, session.UserID, limit)
+	if err != nil {
+		logger.LogError(ctx, "Failed to list generations: %v", err)
+		WriteAPIError(w, ctx, http.StatusInternalServerError, CodeInternalError, "failed to list generations")
+		return
+	}
+
+	if generations == nil {
+		generations = []db.Generation{}
+	}
+
+	WriteJSON(w, ctx, http.StatusOK, generationsResponse{Generations: generations})
+}
+
+type tokenResponse struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// TokenAPIHandler issues a bearer token for the session user, so scripts and mobile clients can
+// call the API without the browser's cookie. The token is a separate session and can be revoked
+// on its own.
+func (s *Service) TokenAPIHandler(w http.ResponseWriter, ctx context.Context, session *db.Session) {
+	token, err := s.newSession(ctx, session.UserID, true)
+	if err != nil {
+		logger.LogError(ctx, "Failed to create API token: %v", err)
+		WriteAPIError(w, ctx, http.StatusInternalServerError, CodeInternalError, "failed to create token")
+		return
+	}
+
+	WriteJSON(w, ctx, http.StatusCreated, tokenResponse{
+		Token:  token.ID,
+		Expiry: token.Expiry,
+	})
+}
+
+// RevokeTokenAPIHandler ends the session the request authenticated with.
+func (s *Service) RevokeTokenAPIHandler(w http.ResponseWriter, ctx context.Context, session *db.Session) {
+	if err := s.Store.DeleteSession(ctx, session.ID); err != nil {
+		logger.LogError(ctx, "Failed to revoke API token: %v", err)
+		WriteAPIError(w, ctx, http.StatusInternalServerError, CodeInternalError, "failed to revoke token")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/match"
+	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+const (
+	// maxArtistReleases caps how many of an artist's albums and singles are generated from.
+	maxArtistReleases = 10
+	// maxConcurrentReleases caps how many releases are generated at once. Each one crawls up to
+	// maxConcurrentCrawls tracks of its own.
+	maxConcurrentReleases = 2
+)
+
+// artistSamples generates a playlist for each of the artist's releases, see artistReleases,
+// concurrently, and merges them in order without repeated tracks. Releases are cached as album
+// seeds, so the merge itself isn't. A release that fails is left out; the artist only fails
+// when every release does.
+func (s *Service) artistSamples(ctx context.Context, spotifyClient *spotify.AuthClient, seed spotify.Resource, options sampled.CrawlOptions, progress progressFunc) (*SeedSamples, error) {
+	seedCtx, cancel := context.WithTimeout(ctx, seedTimeout)
+	defer cancel()
+
+	artist, err := spotifyClient.GetArtist(seedCtx, seed.ID)
+	if err != nil {
+		return nil, seedLookupError(spotify.ArtistResource, seed.ID, err)
+	}
+
+	releases, omitted, err := artistReleases(seedCtx, spotifyClient, seed.ID)
+	if err != nil {
+		return nil, err
+	}
+	if omitted > 0 {
+		logger.LogInfo(ctx, "Leaving %d releases of artist %s out", omitted, seed.ID)
+	}
+
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("%w: artist %s has no albums or singles", ErrNoTracks, seed.ID)
+	}
+
+	var (
+		results = make([]*SeedSamples, len(releases))
+		errs    = make([]error, len(releases))
+		slots   = make(chan struct{}, maxConcurrentReleases)
+		// done and total hold each release's progress, reported summed for the artist
+		done  = make([]int, len(releases))
+		total = make([]int, len(releases))
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+	)
+
+	for index, release := range releases {
+		total[index] = release.TotalTracks
+	}
+
+	// report must be called with mu held
+	report := func() {
+		if progress != nil {
+			var sumDone, sumTotal int
+			for index := range releases {
+				sumDone += done[index]
+				sumTotal += total[index]
+			}
+			progress(sumDone, sumTotal)
+		}
+	}
+
+	mu.Lock()
+	report()
+	mu.Unlock()
+
+	for index, release := range releases {
+		wg.Add(1)
+		go func(index int, release spotify.Album) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			albumSeed := spotify.Resource{Type: spotify.AlbumResource, ID: release.ID}
+			results[index], errs[index] = s.seedSamples(ctx, spotifyClient, albumSeed, options, func(releaseDone, releaseTotal int) {
+				mu.Lock()
+				defer mu.Unlock()
+				done[index] = max(done[index], releaseDone)
+				total[index] = releaseTotal
+				report()
+			})
+
+			// cached releases report no progress of their own
+			mu.Lock()
+			defer mu.Unlock()
+			done[index] = total[index]
+			report()
+		}(index, release)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("artist generation stopped: %w", err)
+	}
+
+	var (
+		tracks     []string
+		provenance []db.TrackProvenance
+		firstErr   error
+	)
+	for index, result := range results {
+		if errs[index] != nil {
+			logger.LogError(ctx, "Leaving %s out of artist %s: %v", releases[index].Name, seed.ID, errs[index])
+			if firstErr == nil {
+				firstErr = errs[index]
+			}
+			continue
+		}
+		tracks = append(tracks, result.Tracks...)
+		provenance = append(provenance, result.Provenance...)
+	}
+
+	tracks = dedupeTracks(tracks)
+	if len(tracks) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, ErrNoTracks
+	}
+
+	return &SeedSamples{
+		Seed:            seed,
+		Name:            artist.Name,
+		Tracks:          tracks,
+		Provenance:      mergeProvenance(tracks, provenance),
+		OmittedReleases: omitted,
+	}, nil
+}
+
+// artistReleases returns the artist's releases to generate from: albums before singles, each
+// newest first, at most maxArtistReleases of them. Deluxe editions, remasters and other
+// versions of a release are left out in favor of its first edition. omitted counts the
+// releases past maxArtistReleases in that order, which are left out.
+func artistReleases(ctx context.Context, spotifyClient *spotify.AuthClient, artistID string) (releases []spotify.Album, omitted int, err error) {
+	albums, err := spotifyClient.GetArtistAlbums(ctx, artistID, spotify.AlbumGroup, spotify.SingleGroup)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the first edition of each release is the oldest one
+	slices.SortStableFunc(albums, func(a, b spotify.Album) int {
+		return strings.Compare(a.ReleaseDate, b.ReleaseDate)
+	})
+
+	seen := make(map[string]bool)
+	for _, album := range albums {
+		name := match.NormalizeTitle(album.Name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		releases = append(releases, album)
+	}
+
+	slices.SortStableFunc(releases, func(a, b spotify.Album) int {
+		aSingle, bSingle := a.AlbumGroup == spotify.SingleGroup, b.AlbumGroup == spotify.SingleGroup
+		switch {
+		case !aSingle && bSingle:
+			return -1
+		case aSingle && !bSingle:
+			return 1
+		}
+		return strings.Compare(b.ReleaseDate, a.ReleaseDate)
+	})
+
+	if len(releases) > maxArtistReleases {
+		omitted = len(releases) - maxArtistReleases
+		releases = releases[:maxArtistReleases]
+	}
+
+	return releases, omitted, nil
+}
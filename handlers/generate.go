@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+// Per-stage deadlines. A slow upstream ends its stage with an error instead of holding the
+// request or job open indefinitely.
+const (
+	// seedTimeout bounds fetching the album or playlist and its track list.
+	seedTimeout = 20 * time.Second
+	// trackCrawlTimeout bounds crawling the sample tree of a single seed track.
+	trackCrawlTimeout = 90 * time.Second
+	// playlistTimeout bounds creating the playlist and adding its tracks.
+	playlistTimeout = 30 * time.Second
+)
+
+// maxConcurrentCrawls caps how many seed tracks are crawled at once, so a long playlist doesn't
+// start every crawl, and its deadline, together.
+const maxConcurrentCrawls = 16
+
+// playlistWriteAttempts is how many times adding a playlist's tracks is tried, each attempt
+// resuming where the last one stopped.
+const playlistWriteAttempts = 3
+
+var (
+	// ErrSeedNotFound is returned when Spotify has no seed for the requested ID, or the seed is
+	// a playlist the user can't see.
+	ErrSeedNotFound = errors.New("seed not found")
+	// ErrNoTracks is returned when a seed produced no playlist tracks.
+	ErrNoTracks = errors.New("no tracks found")
+)
+
+// SeedSamples is the track list generated for a seed, before it is written to a playlist.
+type SeedSamples struct {
+	Seed spotify.Resource
+	// Name is the seed's name on Spotify.
+	Name       string
+	Tracks     []string
+	Provenance []db.TrackProvenance
+	// OmittedReleases counts an artist's releases left out by maxArtistReleases.
+	OmittedReleases int
+}
+
+// userClient returns the stored user and a Spotify client acting on their behalf. Users stored
+// before their market was recorded have it looked up and saved.
+func (s *Service) userClient(ctx context.Context, userID string) (*db.User, *spotify.AuthClient, error) {
+	user, err := s.Store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	spotifyClient := &spotify.AuthClient{
+		Client:      &http.Client{},
+		AccessToken: user.AccessToken,
+		TokenSource: s.tokenSource(ctx, user),
+	}
+
+	if user.Country == "" {
+		// without a market tracks are resolved for any market, so a failure isn't fatal
+		me, err := spotifyClient.GetUser(ctx)
+		if err != nil {
+			logger.LogError(ctx, "Failed to look up market of user %s: %v", user.ID, err)
+		} else if me.Country != "" {
+			user.Country = me.Country
+			if _, err := s.Store.CreateUser(ctx, *user); err != nil {
+				logger.LogError(ctx, "Failed to save market of user %s: %v", user.ID, err)
+			}
+		}
+	}
+
+	return user, spotifyClient, nil
+}
+
+// progressFunc is told how many of a seed's tracks have been crawled so far. It may be called
+// concurrently and out of order.
+type progressFunc func(done, total int)
+
+// seedSamples returns the seed's tracks interleaved with their sample trees. Results are cached
+// per seed and crawl options for a week; artists are merged from their releases, see
+// artistSamples. progress, when set, is called as each seed track's sample tree completes.
+// Cancelling ctx stops every track's crawl.
+func (s *Service) seedSamples(ctx context.Context, spotifyClient *spotify.AuthClient, seed spotify.Resource, options sampled.CrawlOptions, progress progressFunc) (*SeedSamples, error) {
+	if seed.Type == spotify.ArtistResource {
+		return s.artistSamples(ctx, spotifyClient, seed, options, progress)
+	}
+
+	seedCtx, cancel := context.WithTimeout(ctx, seedTimeout)
+	defer cancel()
+
+	source, err := newSeedSource(seedCtx, spotifyClient, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	// check if the seed has been processed in the last week with the same options
+	cacheKey := tracksCacheKey(source.cacheID, options, spotify.MarketFromContext(ctx))
+	var (
+		playlistTracks []string
+		provenance     []db.TrackProvenance
+	)
+
+	cached, err := s.Store.GetTracks(ctx, cacheKey)
+	if err != nil {
+		logger.LogDebug(ctx, "Error occurred at s.Store.GetTracks(ctx, cacheKey): %v", err)
+	} else {
+		playlistTracks = cached.Tracks
+		provenance = cached.Provenance
+	}
+
+	if len(playlistTracks) == 0 {
+		// find tracks
+		logger.LogDebug(ctx, "Tracks not cached")
+
+		roots, err := source.tracks(seedCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("%w: %s %s has no tracks", ErrNoTracks, seed.Type, seed.ID)
+		}
+
+		var (
+			// each group holds a seed track followed by its flattened sample tree
+			trackGroups = make([][]string, len(roots))
+			samples     = make([][]*sampled.SpotifyTrack, len(roots))
+			crawler     = sampled.NewCrawler(s.SampledManager, options)
+			slots       = make(chan struct{}, maxConcurrentCrawls)
+			total       = len(roots)
+			completed   int
+			incomplete  bool
+			mu          sync.Mutex
+			wg          sync.WaitGroup
+		)
+
+		if progress != nil {
+			progress(0, total)
+		}
+
+		for index, root := range roots {
+			// this can be genius, openai, etc. order matters when set in main
+			wg.Add(1)
+			go func(index int, root *sampled.SpotifyTrack) {
+				defer wg.Done()
+
+				slots <- struct{}{}
+				defer func() { <-slots }()
+
+				trackCtx, cancel := context.WithTimeout(ctx, trackCrawlTimeout)
+				defer cancel()
+
+				tree := crawler.Crawl(trackCtx, root)
+
+				mu.Lock()
+				if trackCtx.Err() != nil {
+					logger.LogError(ctx, "Sample crawl for %s by %s stopped early: %v", root.Name, root.Artist, trackCtx.Err())
+					incomplete = true
+				} else if tree.Incomplete {
+					logger.LogError(ctx, "Sample crawl for %s by %s is missing samples that failed to load", root.Name, root.Artist)
+					incomplete = true
+				}
+				trackGroups[index] = tree.Flatten(options.Order)
+				samples[index] = tree.Samples()
+				completed++
+				done := completed
+				mu.Unlock()
+
+				if progress != nil {
+					progress(done, total)
+				}
+			}(index, root)
+		}
+
+		wg.Wait()
+
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s generation stopped: %w", seed.Type, err)
+		}
+
+		playlistTracks = dedupeTracks(slices.Concat(trackGroups...))
+		provenance = trackProvenance(playlistTracks, slices.Concat(samples...))
+
+		// a crawl cut short by its deadline, or missing samples that failed to load, would
+		// otherwise be served from the cache for a week
+		if incomplete {
+			logger.LogInfo(ctx, "Not caching incomplete tracks for %s %s", seed.Type, seed.ID)
+		} else if err := s.Store.SetTracks(ctx, cacheKey, playlistTracks, provenance); err != nil {
+			logger.LogError(ctx, "Failed to set tracks: %v", err)
+		}
+	}
+
+	if len(playlistTracks) == 0 {
+		return nil, ErrNoTracks
+	}
+
+	return &SeedSamples{
+		Seed:       seed,
+		Name:       source.name,
+		Tracks:     playlistTracks,
+		Provenance: provenance,
+	}, nil
+}
+
+// dedupeTracks drops empty URIs and repeated tracks, keeping each track's last occurrence.
+func dedupeTracks(uris []string) []string {
+	last := make(map[string]int, len(uris))
+	for i, uri := range uris {
+		last[uri] = i
+	}
+
+	deduped := make([]string, 0, len(last))
+	for i, uri := range uris {
+		if uri != "" && last[uri] == i {
+			deduped = append(deduped, uri)
+		}
+	}
+	return deduped
+}
+
+// createPlaylist writes the seed's tracks to a new playlist in the user's account and records
+// the generation in their history.
+func (s *Service) createPlaylist(ctx context.Context, spotifyClient *spotify.AuthClient, userID string, samples *SeedSamples, options sampled.CrawlOptions) (*db.Generation, error) {
+	playlistCtx, cancel := context.WithTimeout(ctx, playlistTimeout)
+	defer cancel()
+
+	// Create Spotify playlist
+	playlist := spotify.NewPlaylist{
+		Name:        fmt.Sprintf("Titled - Inspired Songs from %s", samples.Name),
+		Description: "Generated playlist from Titled.",
+		Public:      true,
+	}
+	if options.Reverse {
+		playlist.Name = fmt.Sprintf("Titled - Songs Inspired by %s", samples.Name)
+	}
+	lowConfidence := countLowConfidence(samples.Provenance)
+	if lowConfidence > 0 {
+		playlist.Description = fmt.Sprintf("Generated playlist from Titled. Includes %d low-confidence picks.", lowConfidence)
+	}
+
+	userPlaylist, err := spotifyClient.CreatePlaylist(playlistCtx, userID, playlist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create playlist: %w", err)
+	}
+
+	// a failed write resumes after the last batch that made it into the playlist
+	progress := &spotify.PlaylistProgress{SnapshotID: userPlaylist.SnapshotID}
+	for attempt := 1; ; attempt++ {
+		err = spotifyClient.AddPlaylistItems(playlistCtx, userPlaylist.ID, samples.Tracks, progress)
+		if err == nil {
+			break
+		}
+		if attempt == playlistWriteAttempts || playlistCtx.Err() != nil {
+			return nil, fmt.Errorf("failed to add tracks to playlist after %d of %d: %w", progress.Written, len(samples.Tracks), err)
+		}
+		logger.LogError(ctx, "Adding tracks to playlist %s stopped after %d of %d, resuming: %v", userPlaylist.ID, progress.Written, len(samples.Tracks), err)
+	}
+
+	logger.LogInfo(ctx, "Playlist created. URI: %s, ID: %s", userPlaylist.URI, userPlaylist.ID)
+
+	generation := db.Generation{
+		ID:              generateRandomString(22),
+		UserID:          userID,
+		AlbumID:         samples.Seed.ID,
+		AlbumName:       samples.Name,
+		PlaylistID:      userPlaylist.ID,
+		PlaylistURL:     userPlaylist.ExternalURLs.Spotify,
+		SnapshotID:      progress.SnapshotID,
+		Tracks:          len(samples.Tracks),
+		LowConfidence:   lowConfidence,
+		Reverse:         options.Reverse,
+		CreatedAt:       time.Now(),
+		SeedType:        string(samples.Seed.Type),
+		OmittedReleases: samples.OmittedReleases,
+	}
+
+	// the playlist exists either way, a missing history entry is not worth failing over
+	if err := s.Store.CreateGeneration(ctx, generation); err != nil {
+		logger.LogError(ctx, "Failed to record generation: %v", err)
+	}
+
+	return &generation, nil
+}
@@ -5,19 +5,20 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"slices"
 	"strings"
-	"sync"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/ericflores108/spotify/config"
 	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/genius"
 	"github.com/ericflores108/spotify/htmlpages"
+	"github.com/ericflores108/spotify/jobs"
 	"github.com/ericflores108/spotify/logger"
 	"github.com/ericflores108/spotify/sampled"
 	"github.com/ericflores108/spotify/spotify"
@@ -25,190 +26,83 @@ import (
 
 type Service struct {
 	SampledManager      *sampled.SampledManager
-	Firestore           *firestore.Client
+	Store               db.Store
+	Jobs                *jobs.Pool
+	Progress            *jobs.Broker
 	SpotifyClientID     string
 	SpotifyClientSecret string
 	URL                 string
 	StateKey            string
 }
 
-func (s *Service) GeneratePlaylistHandler(w http.ResponseWriter, ctx context.Context, albumID, userID, accessToken string, r *http.Request) {
-	spotifyClient := &spotify.AuthClient{
-		Client:      &http.Client{},
-		AccessToken: accessToken,
-	}
-
-	album, err := spotifyClient.GetAlbum(albumID)
-	if err != nil {
-		logger.LogError("Failed to get album: %v", err)
-		htmlpages.RenderErrorPage(w, fmt.Sprintf("Failed to get album: %v", err.Error()))
-		return
-	}
-
-	if album == nil {
-		logger.LogError("Failed to get album ID: %s", albumID)
-		htmlpages.RenderErrorPage(w, "Failed to get album.")
-		return
-	}
-
-	// check if album has been processed in the last week
-	playlistTracks, err := db.GetTracks(ctx, s.Firestore, albumID)
+// GeneratePlaylistHandler queues playlist generation for the seed and redirects to the job's
+// results page, which reports progress while the playlist is built.
+func (s *Service) GeneratePlaylistHandler(w http.ResponseWriter, ctx context.Context, seed spotify.Resource, session *db.Session, options sampled.CrawlOptions, r *http.Request) {
+	job, err := s.submitJob(ctx, session.UserID, seed, options)
 	if err != nil {
-		logger.LogDebug("Error occurred at db.GetTracks(ctx, s.Firestore, albumID): %v", err)
-	}
-
-	if len(playlistTracks) == 0 {
-		// find tracks
-		logger.LogDebug("Tracks not cached")
-
-		albumTracks, err := spotifyClient.GetAlbumTracks(albumID)
-		if err != nil {
-			logger.LogError("Failed to get album tracks: %v", err)
-			htmlpages.RenderErrorPage(w, fmt.Sprintf("Failed to get album tracks: %v", err.Error()))
-			return
-		}
-
-		if albumTracks == nil {
-			logger.LogError("Failed to get album tracks for ID: %s", albumID)
-			htmlpages.RenderErrorPage(w, "Failed to get album tracks")
+		logger.LogError(ctx, "Failed to submit job for %s: %v", seed.URI(), err)
+		if errors.Is(err, jobs.ErrQueueFull) {
+			htmlpages.RenderErrorPage(w, "Titled is busy generating other playlists. Please try again in a minute.")
 			return
 		}
-
-		var (
-			spotifyTracks = make([]string, len(albumTracks.Tracks.Items)*2)
-			mu            sync.Mutex
-			wg            sync.WaitGroup
-		)
-
-		for index, track := range albumTracks.Tracks.Items {
-
-			var artist string
-
-			if len(track.Artists) > 0 {
-				artist = track.Artists[0].Name
-			} else {
-				logger.LogDebug("Unknown artist for track %s", track.Name)
-			}
-
-			mu.Lock()
-			spotifyTracks[index*2] = track.URI
-			mu.Unlock()
-
-			// this can be genius, openai, etc. order matters when set in main
-			wg.Add(1)
-			go func(index int, trackName, artist string) {
-				defer wg.Done()
-				for _, source := range s.SampledManager.Sources {
-					spotifyTrack, err := source.GetSample(ctx, track.Name, artist)
-					if err != nil {
-						logger.LogError("Error getting %s by %s sample: %v", track.Name, artist, err)
-						continue
-					}
-
-					if spotifyTrack == nil {
-						continue
-					}
-
-					mu.Lock()
-					spotifyTracks[index*2+1] = spotifyTrack.URI
-					mu.Unlock()
-
-					break
-				}
-			}(index, track.Name, artist)
-		}
-
-		wg.Wait()
-
-		filteredTracksMap := make(map[string]bool)
-		filteredPlaylist := make([]string, 0, len(spotifyTracks))
-
-		for _, uri := range spotifyTracks {
-			if uri == "" {
-				continue
-			}
-
-			// If the URI is already in the slice, remove it
-			if filteredTracksMap[uri] {
-				// Find and remove previous occurrence
-				for i, existingURI := range playlistTracks {
-					if existingURI == uri {
-						playlistTracks = slices.Delete(filteredPlaylist, i, i+1)
-						break // Remove only the first occurrence
-					}
-				}
-			}
-
-			// Append latest occurrence and mark it as seen
-			filteredPlaylist = append(filteredPlaylist, uri)
-			filteredTracksMap[uri] = true
-		}
-		playlistTracks = filteredPlaylist
-
-		err = db.SetTracks(ctx, s.Firestore, albumID, filteredPlaylist)
-		if err != nil {
-			logger.LogError("Failed to set tracks: %v", err)
-		}
-	}
-
-	if len(playlistTracks) == 0 {
-		logger.LogError("Failed to retrieve tracks")
-		htmlpages.RenderErrorPage(w, "Failed to retrieve tracks")
+		htmlpages.RenderErrorPage(w, "Failed to start generating your playlist.")
 		return
 	}
 
-	// Create Spotify playlist
-	playlist := spotify.NewPlaylist{
-		Name:        fmt.Sprintf("Titled - Inspired Songs from %s", album.Name),
-		Description: "Generated playlist from Titled.",
-		Public:      true,
-	}
+	http.Redirect(w, r, "/jobs/"+job.ID, http.StatusSeeOther)
+}
 
-	userPlaylist, err := spotifyClient.CreatePlaylist(userID, playlist)
-	if err != nil {
-		logger.LogError("Failed to create playlist: %v", err)
-		htmlpages.RenderErrorPage(w, fmt.Sprintf("Failed to create playlist: %v", err.Error()))
-		return
+// tracksCacheKey returns the key a seed's tracks are cached under, see seedSource.cacheID.
+// Albums generated with the default options for any market keep the plain album ID so existing
+// cache entries remain valid. Tracks resolved for a market are cached per market.
+func tracksCacheKey(seedID string, options sampled.CrawlOptions, market string) string {
+	key := optionsCacheKey(seedID, options)
+	if market != "" {
+		key += "@" + market
 	}
+	return key
+}
 
-	err = spotifyClient.AddToPlaylist(userPlaylist.ID, playlistTracks, nil)
-	if err != nil {
-		logger.LogError("Failed to add tracks to playlist: %v", err)
-		htmlpages.RenderErrorPage(w, fmt.Sprintf("Failed to add tracks to playlist: %v", err.Error()))
-		return
+func optionsCacheKey(seedID string, options sampled.CrawlOptions) string {
+	names := make([]string, len(options.Relationships))
+	for i, relationship := range options.Relationships {
+		names[i] = string(relationship)
 	}
+	slices.Sort(names)
+	names = slices.Compact(names)
 
-	logger.LogInfo("Playlist created. URI: %s, ID: %s", userPlaylist.URI, userPlaylist.ID)
+	defaultRelationships := len(names) == 0 || (len(names) == 1 && names[0] == string(genius.Samples))
+	if defaultRelationships && options.MaxDepth <= 1 && options.MaxBreadth == 0 && !options.Reverse && options.MinConfidence == 0 && !options.VerifiedOnly {
+		return seedID
+	}
 
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, htmlpages.Playlist, userPlaylist.ExternalURLs.Spotify, userPlaylist.ID)
+	return fmt.Sprintf("%s:%s:%d:%d:%s:%t:%.2f:%t", seedID, strings.Join(names, ","), options.MaxDepth, options.MaxBreadth, options.Order, options.Reverse, options.MinConfidence, options.VerifiedOnly)
 }
 
-func (s *Service) exchangeCodeForToken(code string) (*spotify.TokenResponse, error) {
+func (s *Service) exchangeCodeForToken(ctx context.Context, code string) (*spotify.TokenResponse, error) {
 	// Exchange code for tokens
 	tokenURL := "https://accounts.spotify.com/api/token"
 	data := fmt.Sprintf("grant_type=authorization_code&code=%s&redirect_uri=%s", code, s.URL+"/callback")
-	req, _ := http.NewRequest("POST", tokenURL, strings.NewReader(data))
+	req, _ := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data))
 	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(s.SpotifyClientID+":"+s.SpotifyClientSecret)))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		logger.LogError("Failed to get tokens: %v", err)
+		logger.LogError(ctx, "Failed to get tokens: %v", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		logger.LogError("Failed to get tokens from Spotify.")
+		logger.LogError(ctx, "Failed to get tokens from Spotify.")
 		return nil, fmt.Errorf("failed to get tokens from Spotify")
 	}
 
 	var tokenResponse spotify.TokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
-		logger.LogError("Failed to decode token response: %v", err)
+		logger.LogError(ctx, "Failed to decode token response: %v", err)
 		return nil, err
 	}
 
@@ -221,69 +115,77 @@ func (s *Service) CallbackHandler(w http.ResponseWriter, ctx context.Context, r
 
 	cookie, err := r.Cookie(s.StateKey)
 	if err != nil || cookie.Value != state {
-		logger.LogError("State mismatch error: %v", err)
-		logger.LogDebug("Expected state: %s, Received state: %s", cookie.Value, state)
+		logger.LogError(ctx, "State mismatch error: %v", err)
+		logger.LogDebug(ctx, "Expected state: %s, Received state: %s", cookie.Value, state)
 		http.Error(w, "State mismatch", http.StatusBadRequest)
 		return
 	}
 
-	token, err := s.exchangeCodeForToken(code)
+	token, err := s.exchangeCodeForToken(ctx, code)
 	if err != nil || cookie.Value != state {
-		logger.LogError("s.exchangeCodeForToken(code) error: %v", err)
+		logger.LogError(ctx, "s.exchangeCodeForToken(code) error: %v", err)
 		http.Error(w, "Failed to exchange code for token", http.StatusBadRequest)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "spotify_token",
-		Value:    token.AccessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(time.Hour - time.Minute),
-	})
-
 	spotifyClient := &spotify.AuthClient{
 		Client:      &http.Client{},
 		AccessToken: token.AccessToken,
 	}
 
-	spotifyUser, err := spotifyClient.GetUser()
+	spotifyUser, err := spotifyClient.GetUser(ctx)
 	if err != nil {
-		logger.LogError("Failed to get user from Spotify: %v", err)
+		logger.LogError(ctx, "Failed to get user from Spotify: %v", err)
 		http.Error(w, "Failed to get user from Spotify", http.StatusUnauthorized)
 		return
 	}
+	ctx = logger.With(ctx, logger.UserIDKey, spotifyUser.UserID)
 
 	user := db.User{
 		ID:           spotifyUser.UserID,
 		DisplayName:  spotifyUser.DisplayName,
 		AccessToken:  token.AccessToken,
 		RefreshToken: token.RefreshToken,
+		TokenExpiry:  time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		Country:      spotifyUser.Country,
 	}
 
-	docID, err := db.CreateUser(ctx, s.Firestore, user)
+	docID, err := s.Store.CreateUser(ctx, user)
 	if err != nil {
-		logger.LogError("Failed to create Titled user: %v", err)
+		logger.LogError(ctx, "Failed to create Titled user: %v", err)
 		http.Error(w, "Failed to create Titled user", http.StatusUnauthorized)
 		return
 	}
-	logger.LogDebug("DOC ID: %s", docID)
+	logger.LogDebug(ctx, "DOC ID: %s", docID)
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "_id",
-		Value:    spotifyUser.UserID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
-	})
+	if _, err := s.startSession(w, ctx, user.ID, true); err != nil {
+		logger.LogError(ctx, "Failed to start session: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
 
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
 
+// tokenSource returns a token source for a stored user that persists refreshed tokens.
+func (s *Service) tokenSource(ctx context.Context, user *db.User) *spotify.TokenSource {
+	token := spotify.Token{
+		AccessToken:  user.AccessToken,
+		RefreshToken: user.RefreshToken,
+		Expiry:       user.TokenExpiry,
+	}
+
+	return spotify.NewTokenSource(token, s.SpotifyClientID, s.SpotifyClientSecret, func(token spotify.Token) error {
+		refreshed := *user
+		refreshed.AccessToken = token.AccessToken
+		refreshed.RefreshToken = token.RefreshToken
+		refreshed.TokenExpiry = token.Expiry
+		// use a fresh context, the refresh may outlive the request that triggered it
+		_, err := s.Store.CreateUser(context.WithoutCancel(ctx), refreshed)
+		return err
+	})
+}
+
 func generateRandomString(length int) string {
 	b := make([]byte, length)
 	_, err := rand.Read(b)
@@ -310,64 +212,38 @@ func (s *Service) LoginHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) HomePageHandler(w http.ResponseWriter, ctx context.Context, r *http.Request) {
-	if r.URL.Path == "/spotify" {
-		token, err := db.GetUserByID(ctx, s.Firestore, config.Eflorty108)
-		if err != nil {
-			logger.LogError("Failed to get default user: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-
-		formData := struct {
-			UserID      string
-			AlbumURL    string
-			AccessToken string
-		}{
-			UserID:      config.Eflorty108,
-			AccessToken: token,
-			AlbumURL:    "",
-		}
-
-		tmpl := template.Must(template.New("form").Parse(htmlpages.GeneratePlaylist))
-
-		w.Header().Set("Content-Type", "text/html")
-		if err := tmpl.Execute(w, formData); err != nil {
-			logger.LogError("Failed to render template: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-	} else {
-		userIDCookie, err := r.Cookie("_id")
-		if err != nil {
-			logger.LogError("Failed to get user ID cookie: %v", err)
-			http.Redirect(w, r, "/", http.StatusSeeOther)
-			return
-		}
+	session, err := s.Session(ctx, r)
 
-		accessTokenCookie, err := r.Cookie("spotify_token")
+	if r.URL.Path == "/spotify" {
+		// visitors without a session of their own get a short one in the default user's account
 		if err != nil {
-			logger.LogError("Failed to get access token cookie: %v", err)
-			http.Redirect(w, r, "/", http.StatusSeeOther)
-			return
+			session, err = s.startSession(w, ctx, config.Eflorty108, false)
+			if err != nil {
+				logger.LogError(ctx, "Failed to start default user session: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
 		}
+	} else if err != nil {
+		logger.LogError(ctx, "Failed to get session: %v", err)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
 
-		formData := struct {
-			UserID      string
-			AlbumURL    string
-			AccessToken string
-		}{
-			UserID:      userIDCookie.Value,
-			AccessToken: accessTokenCookie.Value,
-			AlbumURL:    "",
-		}
+	formData := struct {
+		AlbumURL  string
+		CSRFToken string
+	}{
+		AlbumURL:  "",
+		CSRFToken: session.CSRFToken,
+	}
 
-		tmpl := template.Must(template.New("form").Parse(htmlpages.GeneratePlaylist))
+	tmpl := template.Must(template.New("form").Parse(htmlpages.GeneratePlaylist))
 
-		w.Header().Set("Content-Type", "text/html")
-		if err := tmpl.Execute(w, formData); err != nil {
-			logger.LogError("Failed to render template: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.Execute(w, formData); err != nil {
+		logger.LogError(ctx, "Failed to render template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 }
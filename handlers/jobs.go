@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/htmlpages"
+	"github.com/ericflores108/spotify/jobs"
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+const (
+	// heartbeatInterval keeps idle progress streams open through proxies.
+	heartbeatInterval = 15 * time.Second
+	// jobTimeout bounds a whole job, on top of the deadlines of its stages.
+	jobTimeout = 15 * time.Minute
+	// staleJobAge is how long an unfinished job may go without an update before it is taken to
+	// have been lost with the server that ran it. A running job ends within jobTimeout, and a job
+	// queued for longer is failed rather than started.
+	staleJobAge = 2 * jobTimeout
+)
+
+// errJobLost is the error given to unfinished jobs that no server is running anymore.
+var errJobLost = errors.New("the job stopped without finishing, most likely because the server restarted; please try again")
+
+// submitJob stores a queued job for the seed and hands it to the worker pool. It returns
+// jobs.ErrQueueFull when the pool cannot take more work.
+func (s *Service) submitJob(ctx context.Context, userID string, seed spotify.Resource, options sampled.CrawlOptions) (*db.Job, error) {
+	now := time.Now()
+	job := db.Job{
+		ID:        generateRandomString(22),
+		UserID:    userID,
+		AlbumID:   seed.ID,
+		Status:    db.JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+		SeedType:  string(seed.Type),
+	}
+
+	if err := s.Store.SaveJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// the job outlives the request that submitted it, but keeps its logger
+	jobCtx := logger.With(context.WithoutCancel(ctx), logger.JobIDKey, job.ID)
+	task := func() {
+		// SweepJobs may already have failed a job that waited this long
+		if time.Since(job.CreatedAt) > staleJobAge {
+			(&jobTracker{service: s, ctx: jobCtx, job: job}).fail(fmt.Errorf("job waited in the queue for over %s", staleJobAge))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(jobCtx, jobTimeout)
+		defer cancel()
+		s.runJob(ctx, job, options)
+	}
+
+	if err := s.Jobs.Submit(task); err != nil {
+		job.Status = db.JobFailed
+		job.Error = err.Error()
+		job.UpdatedAt = time.Now()
+		if err := s.Store.SaveJob(ctx, job); err != nil {
+			logger.LogError(ctx, "Failed to mark job %s failed: %v", job.ID, err)
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// SweepJobs fails unfinished jobs that haven't been updated for staleJobAge, such as jobs that
+// were queued or running when the server stopped, so their pages and progress streams end. It
+// sweeps right away, then every interval until ctx ends.
+func (s *Service) SweepJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.failStaleJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// failStaleJobs marks unfinished jobs without an update for staleJobAge as failed with
+// errJobLost.
+func (s *Service) failStaleJobs(ctx context.Context) {
+	unfinished, err := s.Store.UnfinishedJobs(ctx)
+	if err != nil {
+		logger.LogError(ctx, "Failed to list unfinished jobs: %v", err)
+		return
+	}
+
+	failed := 0
+	for _, job := range unfinished {
+		if time.Since(job.UpdatedAt) < staleJobAge {
+			continue
+		}
+
+		job.Status = db.JobFailed
+		job.Error = errJobLost.Error()
+		job.UpdatedAt = time.Now()
+		if err := s.Store.SaveJob(ctx, job); err != nil {
+			logger.LogError(ctx, "Failed to mark stale job %s failed: %v", job.ID, err)
+			continue
+		}
+		s.Progress.Publish(job)
+		failed++
+	}
+
+	if failed > 0 {
+		logger.LogInfo(ctx, "Marked %d stale jobs failed", failed)
+	}
+}
+
+// jobTracker serializes updates to a running job, persisting and publishing each one.
+type jobTracker struct {
+	service *Service
+	ctx     context.Context
+	mu      sync.Mutex
+	job     db.Job
+}
+
+func (t *jobTracker) update(change func(job *db.Job)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	change(&t.job)
+	t.job.UpdatedAt = time.Now()
+
+	// record the final state even when the job ran out of time
+	if err := t.service.Store.SaveJob(context.WithoutCancel(t.ctx), t.job); err != nil {
+		logger.LogError(t.ctx, "Failed to save job %s: %v", t.job.ID, err)
+	}
+	t.service.Progress.Publish(t.job)
+}
+
+func (t *jobTracker) fail(err error) {
+	logger.LogError(t.ctx, "Job %s failed: %v", t.job.ID, err)
+	t.update(func(job *db.Job) {
+		job.Status = db.JobFailed
+		job.Error = err.Error()
+	})
+}
+
+// jobSeed returns what the job generates its playlist from. Jobs without a seed type predate
+// other seeds and are albums.
+func jobSeed(job db.Job) spotify.Resource {
+	seedType := spotify.ResourceType(job.SeedType)
+	if seedType == "" {
+		seedType = spotify.AlbumResource
+	}
+	return spotify.Resource{Type: seedType, ID: job.AlbumID}
+}
+
+// runJob generates the job's playlist on a pool worker.
+func (s *Service) runJob(ctx context.Context, job db.Job, options sampled.CrawlOptions) {
+	tracker := &jobTracker{
+		service: s,
+		ctx:     ctx,
+		job:     job,
+	}
+
+	// a panicking job must not take the worker, or the server, down with it
+	defer func() {
+		if r := recover(); r != nil {
+			tracker.fail(fmt.Errorf("internal error: %v", r))
+		}
+	}()
+
+	tracker.update(func(job *db.Job) {
+		job.Status = db.JobRunning
+	})
+
+	user, spotifyClient, err := s.userClient(ctx, job.UserID)
+	if err != nil {
+		tracker.fail(err)
+		return
+	}
+	ctx = spotify.WithMarket(ctx, user.Country)
+
+	samples, err := s.seedSamples(ctx, spotifyClient, jobSeed(job), options, func(done, total int) {
+		tracker.update(func(job *db.Job) {
+			job.TracksTotal = total
+			job.TracksDone = max(job.TracksDone, done)
+		})
+	})
+	if err != nil {
+		tracker.fail(err)
+		return
+	}
+
+	generation, err := s.createPlaylist(ctx, spotifyClient, user.ID, samples, options)
+	if err != nil {
+		tracker.fail(err)
+		return
+	}
+
+	tracker.update(func(job *db.Job) {
+		job.Status = db.JobDone
+		job.AlbumName = generation.AlbumName
+		job.TracksDone = job.TracksTotal
+		job.PlaylistID = generation.PlaylistID
+		job.PlaylistURL = generation.PlaylistURL
+		job.Provenance = samples.Provenance
+		job.OmittedReleases = samples.OmittedReleases
+	})
+}
+
+// userJob returns the job if it belongs to the session's user. Other users' jobs are reported
+// as not found.
+func (s *Service) userJob(ctx context.Context, session *db.Session, jobID string) (*db.Job, error) {
+	job, err := s.Store.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.UserID != session.UserID {
+		return nil, fmt.Errorf("job %w", db.ErrNotFound)
+	}
+
+	return job, nil
+}
+
+// JobPageHandler renders a job's results page. While the job runs the page follows its
+// progress stream and reloads once it finishes.
+func (s *Service) JobPageHandler(w http.ResponseWriter, ctx context.Context, session *db.Session, jobID string) {
+	job, err := s.userJob(ctx, session, jobID)
+	if err != nil {
+		logger.LogError(ctx, "Failed to get job %s: %v", jobID, err)
+		htmlpages.RenderErrorPage(w, "Playlist not found.")
+		return
+	}
+
+	tmpl := template.Must(template.New("playlist").Funcs(template.FuncMap{
+		"lowConfidence": func(confidence float64) bool { return confidence < sampled.LowConfidence },
+	}).Parse(htmlpages.Playlist))
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.Execute(w, job); err != nil {
+		logger.LogError(ctx, "Failed to render template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// JobEventsHandler streams a job's state as Server-Sent Events until the job finishes or ctx,
+// the request's context, ends. Each "job" event carries the full job as JSON.
+func (s *Service) JobEventsHandler(w http.ResponseWriter, ctx context.Context, session *db.Session, jobID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteAPIError(w, ctx, http.StatusInternalServerError, CodeInternalError, "streaming is not supported")
+		return
+	}
+
+	// subscribe before reading the stored state so no update falls in between
+	updates, unsubscribe := s.Progress.Subscribe(jobID)
+	defer unsubscribe()
+
+	job, err := s.userJob(ctx, session, jobID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			WriteAPIError(w, ctx, http.StatusNotFound, CodeNotFound, "job not found")
+			return
+		}
+		logger.LogError(ctx, "Failed to get job %s: %v", jobID, err)
+		WriteAPIError(w, ctx, http.StatusInternalServerError, CodeInternalError, "failed to get job")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(job db.Job) bool {
+		data, err := json.Marshal(job)
+		if err != nil {
+			logger.LogError(ctx, "Failed to encode job %s: %v", job.ID, err)
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: job\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return !job.Finished()
+	}
+
+	if !send(*job) {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-updates:
+			if !send(job) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// SubmitJobAPIHandler queues playlist generation for the seed and responds with the job.
+func (s *Service) SubmitJobAPIHandler(w http.ResponseWriter, ctx context.Context, session *db.Session, seed spotify.Resource, options sampled.CrawlOptions) {
+	job, err := s.submitJob(ctx, session.UserID, seed, options)
+	if err != nil {
+		logger.LogError(ctx, "Failed to submit job for %s: %v", seed.URI(), err)
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", "30")
+			WriteAPIError(w, ctx, http.StatusServiceUnavailable, CodeUnavailable, "too many playlists are being generated, try again shortly")
+			return
+		}
+		WriteAPIError(w, ctx, http.StatusInternalServerError, CodeInternalError, "failed to submit job")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	WriteJSON(w, ctx, http.StatusAccepted, job)
+}
+
+// JobAPIHandler responds with the job's current state.
+func (s *Service) JobAPIHandler(w http.ResponseWriter, ctx context.Context, session *db.Session, jobID string) {
+	job, err := s.userJob(ctx, session, jobID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			WriteAPIError(w, ctx, http.StatusNotFound, CodeNotFound, "job not found")
+			return
+		}
+		logger.LogError(ctx, "Failed to get job %s: %v", jobID, err)
+		WriteAPIError(w, ctx, http.StatusInternalServerError, CodeInternalError, "failed to get job")
+		return
+	}
+
+	WriteJSON(w, ctx, http.StatusOK, job)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/sampled"
+)
+
+// trackProvenance returns the provenance of every sample that made it into the playlist,
+// in playlist order. Album tracks have no provenance and are skipped.
+func trackProvenance(playlistTracks []string, samples []*sampled.SpotifyTrack) []db.TrackProvenance {
+	byURI := make(map[string]*sampled.SpotifyTrack, len(samples))
+	for _, sample := range samples {
+		if _, ok := byURI[sample.URI]; !ok {
+			byURI[sample.URI] = sample
+		}
+	}
+
+	var provenance []db.TrackProvenance
+	for _, uri := range playlistTracks {
+		sample, ok := byURI[uri]
+		if !ok {
+			continue
+		}
+
+		provenance = append(provenance, db.NewTrackProvenance(sample))
+	}
+
+	return provenance
+}
+
+// countLowConfidence returns how many samples fall below sampled.LowConfidence.
+func countLowConfidence(provenance []db.TrackProvenance) int {
+	var count int
+	for _, entry := range provenance {
+		if entry.Confidence < sampled.LowConfidence {
+			count++
+		}
+	}
+	return count
+}
+
+// mergeProvenance returns the provenance of every sample that made it into a playlist merged
+// from several, in playlist order. A sample found by more than one keeps its first entry.
+func mergeProvenance(playlistTracks []string, provenance []db.TrackProvenance) []db.TrackProvenance {
+	byURI := make(map[string]db.TrackProvenance, len(provenance))
+	for _, entry := range provenance {
+		if _, ok := byURI[entry.URI]; !ok {
+			byURI[entry.URI] = entry
+		}
+	}
+
+	var merged []db.TrackProvenance
+	for _, uri := range playlistTracks {
+		if entry, ok := byURI[uri]; ok {
+			merged = append(merged, entry)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+// maxSeedTracks caps how many tracks of a playlist are crawled. Every track is a crawl of its
+// own, so longer playlists only contribute their first maxSeedTracks tracks.
+const maxSeedTracks = 100
+
+// ErrUnsupportedSeed is returned for a resource playlists can't be generated from.
+var ErrUnsupportedSeed = errors.New("unsupported seed")
+
+// seedSource is an album, playlist or track as the pipeline sees it. Artists are generated
+// from their releases instead, see artistSamples.
+type seedSource struct {
+	// name is the seed's name on Spotify, used to name the generated playlist.
+	name string
+	// cacheID identifies the seed's current contents in the tracks cache.
+	cacheID string
+	// tracks fetches the tracks whose sample trees make up the playlist. It is only called
+	// when nothing is cached.
+	tracks func(ctx context.Context) ([]*sampled.SpotifyTrack, error)
+}
+
+// newSeedSource looks up the seed on Spotify.
+func newSeedSource(ctx context.Context, spotifyClient *spotify.AuthClient, seed spotify.Resource) (*seedSource, error) {
+	switch seed.Type {
+	case spotify.AlbumResource:
+		return albumSource(ctx, spotifyClient, seed.ID)
+	case spotify.PlaylistResource:
+		return playlistSource(ctx, spotifyClient, seed.ID)
+	case spotify.TrackResource:
+		return trackSource(ctx, spotifyClient, seed.ID)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSeed, seed.Type)
+	}
+}
+
+// albumSource reads an album seed. Albums are cached under their ID alone, as they were before
+// other seeds existed.
+func albumSource(ctx context.Context, spotifyClient *spotify.AuthClient, albumID string) (*seedSource, error) {
+	album, err := spotifyClient.GetAlbum(ctx, albumID)
+	if err != nil {
+		return nil, seedLookupError(spotify.AlbumResource, albumID, err)
+	}
+
+	if album == nil {
+		return nil, fmt.Errorf("%w: album %s", ErrSeedNotFound, albumID)
+	}
+
+	return &seedSource{
+		name:    album.Name,
+		cacheID: albumID,
+		tracks: func(ctx context.Context) ([]*sampled.SpotifyTrack, error) {
+			albumTracks, err := spotifyClient.GetAlbumTracks(ctx, albumID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get album tracks: %w", err)
+			}
+
+			roots := make([]*sampled.SpotifyTrack, len(albumTracks))
+			for i, track := range albumTracks {
+				roots[i] = rootTrack(ctx, track.Name, track.Artists, track.URI)
+			}
+			return roots, nil
+		},
+	}, nil
+}
+
+// playlistSource reads a playlist seed. Playlists are cached per snapshot, so an edited playlist
+// is crawled again.
+func playlistSource(ctx context.Context, spotifyClient *spotify.AuthClient, playlistID string) (*seedSource, error) {
+	playlist, err := spotifyClient.GetPlaylist(ctx, playlistID)
+	if err != nil {
+		return nil, seedLookupError(spotify.PlaylistResource, playlistID, err)
+	}
+
+	return &seedSource{
+		name:    playlist.Name,
+		cacheID: fmt.Sprintf("playlist:%s:%s", playlistID, playlist.SnapshotID),
+		tracks: func(ctx context.Context) ([]*sampled.SpotifyTrack, error) {
+			limited := *spotifyClient
+			limited.MaxItems = maxSeedTracks
+
+			playlistTracks, err := limited.GetPlaylistTracks(ctx, playlistID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get playlist tracks: %w", err)
+			}
+
+			roots := make([]*sampled.SpotifyTrack, len(playlistTracks))
+			for i, track := range playlistTracks {
+				roots[i] = rootTrack(ctx, track.Name, track.Artists, track.URI)
+			}
+			return roots, nil
+		},
+	}, nil
+}
+
+// trackSource reads a track seed, whose playlist is the track's own sample tree.
+func trackSource(ctx context.Context, spotifyClient *spotify.AuthClient, trackID string) (*seedSource, error) {
+	track, err := spotifyClient.GetTrack(ctx, trackID)
+	if err != nil {
+		return nil, seedLookupError(spotify.TrackResource, trackID, err)
+	}
+
+	root := rootTrack(ctx, track.Name, track.Artists, track.URI)
+	return &seedSource{
+		name:    track.Name,
+		cacheID: "track:" + trackID,
+		tracks: func(ctx context.Context) ([]*sampled.SpotifyTrack, error) {
+			return []*sampled.SpotifyTrack{root}, nil
+		},
+	}, nil
+}
+
+// seedLookupError wraps an error looking up a seed, reporting a 404 from Spotify as
+// ErrSeedNotFound.
+func seedLookupError(seedType spotify.ResourceType, id string, err error) error {
+	if errors.Is(err, spotify.ErrNotFound) {
+		return fmt.Errorf("%w: %s %s", ErrSeedNotFound, seedType, id)
+	}
+	return fmt.Errorf("failed to get %s: %w", seedType, err)
+}
+
+// rootTrack returns the root of a seed track's sample tree, credited to its first artist.
+func rootTrack(ctx context.Context, name string, artists []spotify.Artist, uri string) *sampled.SpotifyTrack {
+	var artist string
+	if len(artists) > 0 {
+		artist = artists[0].Name
+	} else {
+		logger.LogDebug(ctx, "Unknown artist for track %s", name)
+	}
+
+	return &sampled.SpotifyTrack{Name: name, Artist: artist, URI: uri}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/logger"
+)
+
+// SessionCookie holds the opaque session ID. Everything else about the session stays on the server.
+const SessionCookie = "titled_session"
+
+// newSession creates and stores a session for the user. authenticated marks a user who signed
+// in with Spotify, see db.Session.
+func (s *Service) newSession(ctx context.Context, userID string, authenticated bool) (*db.Session, error) {
+	ttl := db.SessionTTL
+	if !authenticated {
+		ttl = db.AnonymousSessionTTL
+	}
+
+	session := db.Session{
+		ID:            generateRandomString(43),
+		UserID:        userID,
+		CSRFToken:     generateRandomString(43),
+		Expiry:        time.Now().Add(ttl),
+		Authenticated: authenticated,
+	}
+
+	if err := s.Store.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// startSession creates a session for the user and sets the session cookie.
+func (s *Service) startSession(w http.ResponseWriter, ctx context.Context, userID string, authenticated bool) (*db.Session, error) {
+	session, err := s.newSession(ctx, userID, authenticated)
+	if err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    session.ID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  session.Expiry,
+	})
+
+	return session, nil
+}
+
+// SweepSessions deletes expired sessions every interval until ctx ends, so visitors' short
+// sessions in the default account don't pile up in the store.
+func (s *Service) SweepSessions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.Store.DeleteExpiredSessions(ctx)
+			if err != nil {
+				logger.LogError(ctx, "Failed to delete expired sessions: %v", err)
+				continue
+			}
+			logger.LogDebug(ctx, "Deleted %d expired sessions", deleted)
+		}
+	}
+}
+
+// Session returns the session for the request's session cookie.
+func (s *Service) Session(ctx context.Context, r *http.Request) (*db.Session, error) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil, fmt.Errorf("no session cookie: %w", err)
+	}
+
+	return s.Store.GetSession(ctx, cookie.Value)
+}
+
+// BearerSession returns the session for the request's bearer token. API clients that cannot
+// hold cookies send a session ID issued by the token endpoint instead.
+func (s *Service) BearerSession(ctx context.Context, r *http.Request) (*db.Session, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, errors.New("no bearer token")
+	}
+
+	return s.Store.GetSession(ctx, token)
+}
+
+// ValidCSRFToken reports whether a submitted form token matches the session's.
+func ValidCSRFToken(session *db.Session, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(session.CSRFToken), []byte(token)) == 1
+}
+
+// LogoutHandler ends the current session.
+func (s *Service) LogoutHandler(w http.ResponseWriter, ctx context.Context, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		if err := s.Store.DeleteSession(ctx, cookie.Value); err != nil {
+			logger.LogError(ctx, "Failed to delete session: %v", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,221 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/handlers"
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+// csrfHeader carries the session's CSRF token on cookie-authenticated API writes.
+const csrfHeader = "X-CSRF-Token"
+
+// maxAPIBody caps JSON request bodies.
+const maxAPIBody = 1 << 20
+
+// apiHandlerFunc is an API handler for an authenticated session.
+type apiHandlerFunc func(w http.ResponseWriter, r *http.Request, session *db.Session)
+
+// registerAPIRoutes adds the versioned JSON API. Every response, including errors, is JSON.
+func (s *Server) registerAPIRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/samples", s.apiAuth(s.samples()))
+	mux.HandleFunc("GET /api/v1/albums/{id}/samples", s.apiAuth(s.seedSamples(spotify.AlbumResource)))
+	mux.HandleFunc("GET /api/v1/playlists/{id}/samples", s.apiAuth(s.seedSamples(spotify.PlaylistResource)))
+	mux.HandleFunc("GET /api/v1/tracks/{id}/samples", s.apiAuth(s.seedSamples(spotify.TrackResource)))
+	mux.HandleFunc("GET /api/v1/artists/{id}/samples", s.apiAuth(s.seedSamples(spotify.ArtistResource)))
+	mux.HandleFunc("POST /api/v1/playlists", s.apiAuth(s.createPlaylist()))
+	mux.HandleFunc("GET /api/v1/generations", s.apiAuth(s.generations()))
+	mux.HandleFunc("POST /api/v1/jobs", s.apiAuth(s.submitJob()))
+	mux.HandleFunc("GET /api/v1/jobs/{id}", s.apiAuth(func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		s.Handler.JobAPIHandler(w, r.Context(), session, r.PathValue("id"))
+	}))
+	mux.HandleFunc("GET /api/v1/jobs/{id}/events", s.apiAuth(func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		s.Handler.JobEventsHandler(w, r.Context(), session, r.PathValue("id"))
+	}))
+	mux.HandleFunc("POST /api/v1/tokens", s.apiAuth(func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		s.Handler.TokenAPIHandler(w, r.Context(), session)
+	}))
+	mux.HandleFunc("DELETE /api/v1/tokens", s.apiAuth(func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		s.Handler.RevokeTokenAPIHandler(w, r.Context(), session)
+	}))
+
+	// keep unknown API paths and methods out of the HTML catch-all
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.WriteAPIError(w, r.Context(), http.StatusNotFound, handlers.CodeNotFound, "no such endpoint: "+r.Method+" "+r.URL.Path)
+	})
+}
+
+// apiAuth resolves the request's session from a bearer token or the session cookie. Only
+// sessions of users who signed in with Spotify may use the API; the session visitors share in
+// the default account may not. Browser requests authenticated by cookie must send the CSRF
+// token in a header on anything but GET.
+func (s *Server) apiAuth(next apiHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if r.Header.Get("Authorization") != "" {
+			session, err := s.Handler.BearerSession(ctx, r)
+			if err != nil || !session.Authenticated {
+				logger.LogDebug(ctx, "Failed to get bearer session: %v", err)
+				handlers.WriteAPIError(w, ctx, http.StatusUnauthorized, handlers.CodeUnauthorized, "invalid or expired token")
+				return
+			}
+			next(w, withSession(r, session), session)
+			return
+		}
+
+		session, err := s.Handler.Session(ctx, r)
+		if err != nil {
+			logger.LogDebug(ctx, "Failed to get session: %v", err)
+			handlers.WriteAPIError(w, ctx, http.StatusUnauthorized, handlers.CodeUnauthorized, "authentication required")
+			return
+		}
+
+		if !session.Authenticated {
+			handlers.WriteAPIError(w, ctx, http.StatusForbidden, handlers.CodeForbidden, "sign in with Spotify to use the API")
+			return
+		}
+
+		if r.Method != http.MethodGet && !handlers.ValidCSRFToken(session, r.Header.Get(csrfHeader)) {
+			handlers.WriteAPIError(w, ctx, http.StatusForbidden, handlers.CodeForbidden, "missing or invalid "+csrfHeader+" header")
+			return
+		}
+
+		next(w, withSession(r, session), session)
+	}
+}
+
+// samples looks up the samples of a single song given by the song and artist query parameters.
+func (s *Server) samples() apiHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		query := r.URL.Query()
+		song, artist := query.Get("song"), query.Get("artist")
+		if song == "" || artist == "" {
+			handlers.WriteAPIError(w, r.Context(), http.StatusBadRequest, handlers.CodeBadRequest, "song and artist are required")
+			return
+		}
+
+		relationships, err := parseRelationships(query["relationships"])
+		if err != nil {
+			handlers.WriteAPIError(w, r.Context(), http.StatusBadRequest, handlers.CodeBadRequest, err.Error())
+			return
+		}
+
+		s.Handler.SamplesAPIHandler(w, r.Context(), song, artist, relationships)
+	}
+}
+
+// seedSamples previews the playlist for a seed of the given type. Crawl settings use the form's
+// field names as query parameters, plus reverse.
+func (s *Server) seedSamples(seedType spotify.ResourceType) apiHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		if err := r.ParseForm(); err != nil {
+			handlers.WriteAPIError(w, r.Context(), http.StatusBadRequest, handlers.CodeBadRequest, "failed to parse query")
+			return
+		}
+
+		options, err := crawlOptions(r)
+		if err != nil {
+			handlers.WriteAPIError(w, r.Context(), http.StatusBadRequest, handlers.CodeBadRequest, err.Error())
+			return
+		}
+		options.Reverse = r.FormValue("reverse") != ""
+
+		seed := spotify.Resource{Type: seedType, ID: r.PathValue("id")}
+		if !spotify.ValidID(seed.ID) {
+			handlers.WriteAPIError(w, r.Context(), http.StatusBadRequest, handlers.CodeBadRequest, "invalid Spotify ID")
+			return
+		}
+
+		ctx := logger.With(r.Context(), logger.SeedKey, seed.URI())
+		s.Handler.SeedSamplesAPIHandler(w, ctx, session, seed, options)
+	}
+}
+
+// createPlaylist creates a playlist from a JSON playlistRequest, waiting for it to finish.
+func (s *Server) createPlaylist() apiHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		seed, options, ok := decodePlaylistRequest(w, r)
+		if !ok {
+			return
+		}
+
+		ctx := logger.With(r.Context(), logger.SeedKey, seed.URI())
+		s.Handler.CreatePlaylistAPIHandler(w, ctx, session, seed, options)
+	}
+}
+
+// submitJob queues a playlist from a JSON playlistRequest and responds with the job at once.
+func (s *Server) submitJob() apiHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		seed, options, ok := decodePlaylistRequest(w, r)
+		if !ok {
+			return
+		}
+
+		ctx := logger.With(r.Context(), logger.SeedKey, seed.URI())
+		s.Handler.SubmitJobAPIHandler(w, ctx, session, seed, options)
+	}
+}
+
+// decodePlaylistRequest reads and validates a JSON playlistRequest. When it fails the error
+// response has already been written.
+func decodePlaylistRequest(w http.ResponseWriter, r *http.Request) (spotify.Resource, sampled.CrawlOptions, bool) {
+	ctx := r.Context()
+
+	var request playlistRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBody)).Decode(&request); err != nil {
+		handlers.WriteAPIError(w, ctx, http.StatusBadRequest, handlers.CodeBadRequest, "invalid JSON body: "+err.Error())
+		return spotify.Resource{}, sampled.CrawlOptions{}, false
+	}
+
+	link := request.Seed
+	if link == "" {
+		link = request.Album
+	} else if request.Album != "" {
+		handlers.WriteAPIError(w, ctx, http.StatusBadRequest, handlers.CodeBadRequest, "give either seed or album, not both")
+		return spotify.Resource{}, sampled.CrawlOptions{}, false
+	}
+
+	seed, err := seedResource(ctx, link)
+	if err != nil {
+		if status := linkErrorStatus(err); status != http.StatusBadRequest {
+			handlers.WriteAPIError(w, ctx, status, handlers.CodeUpstreamError, err.Error())
+			return spotify.Resource{}, sampled.CrawlOptions{}, false
+		}
+		handlers.WriteAPIError(w, ctx, http.StatusBadRequest, handlers.CodeBadRequest, err.Error())
+		return spotify.Resource{}, sampled.CrawlOptions{}, false
+	}
+
+	options, err := request.crawlOptions()
+	if err != nil {
+		handlers.WriteAPIError(w, ctx, http.StatusBadRequest, handlers.CodeBadRequest, err.Error())
+		return spotify.Resource{}, sampled.CrawlOptions{}, false
+	}
+
+	logger.LogInfo(ctx, "Seed submitted through API: %s", link)
+
+	return seed, options, true
+}
+
+// generations lists past generations, limited by the optional limit query parameter.
+func (s *Server) generations() apiHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, session *db.Session) {
+		var limit int
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				handlers.WriteAPIError(w, r.Context(), http.StatusBadRequest, handlers.CodeBadRequest, "limit must be a positive integer")
+				return
+			}
+			limit = parsed
+		}
+
+		s.Handler.GenerationsAPIHandler(w, r.Context(), session, limit)
+	}
+}
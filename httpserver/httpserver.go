@@ -2,18 +2,31 @@ package httpserver
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"fmt"
 	"html/template"
 	"net/http"
-	"strings"
+	"strconv"
 
+	"github.com/ericflores108/spotify/genius"
 	"github.com/ericflores108/spotify/handlers"
 	"github.com/ericflores108/spotify/htmlpages"
 	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+const (
+	maxCrawlDepth   = 4
+	maxCrawlBreadth = 10
 )
 
 type Server struct {
 	Handler *handlers.Service
+	// Debug serves expvar counters at /debug/vars. They include the process's command line and
+	// memory stats, so it is only set when running locally.
+	Debug bool
 }
 
 func NewServer(handler *handlers.Service) *Server {
@@ -22,9 +35,16 @@ func NewServer(handler *handlers.Service) *Server {
 	}
 }
 
-func (s *Server) RegisterRoutes(ctx context.Context) *http.ServeMux {
+// RegisterRoutes returns the app's handler. Every request gets a logger tagged with its
+// request ID and trace.
+func (s *Server) RegisterRoutes() http.Handler {
 	mux := http.NewServeMux()
 
+	// Request and throttling counters, e.g. for the Spotify API
+	if s.Debug {
+		mux.Handle("GET /debug/vars", expvar.Handler())
+	}
+
 	// Serve static files
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
 
@@ -42,12 +62,15 @@ func (s *Server) RegisterRoutes(ctx context.Context) *http.ServeMux {
 	// Authentication routes
 	mux.HandleFunc("/login", s.Handler.LoginHandler)
 	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		s.Handler.CallbackHandler(w, ctx, r)
+		s.Handler.CallbackHandler(w, r.Context(), r)
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		s.Handler.LogoutHandler(w, r.Context(), r)
 	})
 
 	// Spotify route doesn't need auth. It creates a playlist only in eflorty
 	mux.HandleFunc("/spotify", func(w http.ResponseWriter, r *http.Request) {
-		s.Handler.HomePageHandler(w, ctx, r)
+		s.Handler.HomePageHandler(w, r.Context(), r)
 	})
 
 	// Protected routes with middleware
@@ -57,9 +80,44 @@ func (s *Server) RegisterRoutes(ctx context.Context) *http.ServeMux {
 			http.Error(w, "You must accept cookies to use this site.", http.StatusForbidden)
 			return
 		}
-		s.Handler.HomePageHandler(w, ctx, r)
+		s.Handler.HomePageHandler(w, r.Context(), r)
+	})
+	mux.HandleFunc("/generatePlaylist", s.generatePlaylist(false))
+
+	// Reverse lookup: songs that later sampled the album's tracks
+	mux.HandleFunc("/generateLegacyPlaylist", s.generatePlaylist(true))
+
+	// Results page and progress stream for queued generations
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		session, err := s.Handler.Session(r.Context(), r)
+		if err != nil {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		r = withSession(r, session)
+		s.Handler.JobPageHandler(w, r.Context(), session, r.PathValue("id"))
 	})
-	mux.HandleFunc("/generatePlaylist", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("GET /jobs/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		session, err := s.Handler.Session(r.Context(), r)
+		if err != nil {
+			http.Error(w, "Please log in again", http.StatusUnauthorized)
+			return
+		}
+		r = withSession(r, session)
+		s.Handler.JobEventsHandler(w, r.Context(), session, r.PathValue("id"))
+	})
+
+	// JSON API for scripts and mobile clients
+	s.registerAPIRoutes(mux)
+
+	return withRequestLogger(mux)
+}
+
+// generatePlaylist handles the playlist form, which takes an album, playlist, track or artist.
+// When reverse is set the playlist is built from the songs that borrowed from the seed instead
+// of the songs the seed borrows from.
+func (s *Server) generatePlaylist(reverse bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 			return
@@ -70,29 +128,160 @@ func (s *Server) RegisterRoutes(ctx context.Context) *http.ServeMux {
 			return
 		}
 
-		userID := r.FormValue("userID")
+		session, err := s.Handler.Session(r.Context(), r)
+		if err != nil {
+			logger.LogError(r.Context(), "Failed to get session: %v", err)
+			http.Error(w, "Please log in again", http.StatusUnauthorized)
+			return
+		}
+		r = withSession(r, session)
+
+		if !handlers.ValidCSRFToken(session, r.FormValue("csrfToken")) {
+			http.Error(w, "Invalid form token", http.StatusForbidden)
+			return
+		}
+
 		albumURL := r.FormValue("albumURL")
-		accessToken := r.FormValue("accessToken")
 
-		if userID == "" || albumURL == "" || accessToken == "" {
+		if albumURL == "" {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
 
-		logger.InfoLogger.SetPrefix(fmt.Sprintf("UserID: %s", userID))
-		logger.DebugLogger.SetPrefix(fmt.Sprintf("UserID: %s", userID))
-		logger.ErrorLogger.SetPrefix(fmt.Sprintf("UserID: %s", userID))
+		options, err := crawlOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.Reverse = reverse
 
-		logger.LogInfo("Album link submitted: %s", albumURL)
+		logger.LogInfo(r.Context(), "Link submitted: %s", albumURL)
 
-		parts := strings.Split(albumURL, "/album/")
-		if len(parts) < 2 {
-			http.Error(w, "Invalid URL format", http.StatusBadRequest)
+		seed, err := seedResource(r.Context(), albumURL)
+		if err != nil {
+			logger.LogError(r.Context(), "Failed to read link %s: %v", albumURL, err)
+			http.Error(w, err.Error(), linkErrorStatus(err))
 			return
 		}
 
-		s.Handler.GeneratePlaylistHandler(w, ctx, parts[1], userID, accessToken, r)
-	})
+		ctx := logger.With(r.Context(), logger.SeedKey, seed.URI())
+		s.Handler.GeneratePlaylistHandler(w, ctx, seed, session, options, r)
+	}
+}
+
+// crawlOptions reads the sample tree settings from a parsed form.
+func crawlOptions(r *http.Request) (sampled.CrawlOptions, error) {
+	request := playlistRequest{
+		Relationships:        r.Form["relationships"],
+		Order:                r.FormValue("order"),
+		ExcludeLowConfidence: r.FormValue("excludeLowConfidence") != "",
+		VerifiedOnly:         r.FormValue("verifiedOnly") != "",
+	}
 
-	return mux
+	if depth := r.FormValue("depth"); depth != "" {
+		value, err := strconv.Atoi(depth)
+		if err != nil || value < 1 {
+			return sampled.CrawlOptions{}, fmt.Errorf("depth must be between 1 and %d", maxCrawlDepth)
+		}
+		request.Depth = value
+	}
+
+	if breadth := r.FormValue("breadth"); breadth != "" {
+		value, err := strconv.Atoi(breadth)
+		if err != nil {
+			return sampled.CrawlOptions{}, fmt.Errorf("breadth must be between 0 and %d", maxCrawlBreadth)
+		}
+		request.Breadth = value
+	}
+
+	return request.crawlOptions()
+}
+
+// playlistRequest holds the playlist settings shared by the form and the JSON API. The JSON
+// field names match the form's.
+type playlistRequest struct {
+	// Seed is the album, playlist, track or artist link, URI or ID to generate from.
+	Seed string `json:"seed"`
+	// Album is Seed's older name, still accepted.
+	Album                string   `json:"album"`
+	Relationships        []string `json:"relationships"`
+	Depth                int      `json:"depth"`
+	Breadth              int      `json:"breadth"`
+	Order                string   `json:"order"`
+	Reverse              bool     `json:"reverse"`
+	ExcludeLowConfidence bool     `json:"excludeLowConfidence"`
+	VerifiedOnly         bool     `json:"verifiedOnly"`
+}
+
+// crawlOptions validates the request's sample tree settings. Missing values fall back to
+// samples only, a single hop with no breadth limit, flattened depth-first.
+func (p playlistRequest) crawlOptions() (sampled.CrawlOptions, error) {
+	relationships, err := parseRelationships(p.Relationships)
+	if err != nil {
+		return sampled.CrawlOptions{}, err
+	}
+
+	options := sampled.CrawlOptions{
+		Relationships: relationships,
+		MaxDepth:      1,
+		Order:         sampled.DepthFirst,
+		Reverse:       p.Reverse,
+	}
+
+	if p.Depth != 0 {
+		if p.Depth < 1 || p.Depth > maxCrawlDepth {
+			return options, fmt.Errorf("depth must be between 1 and %d", maxCrawlDepth)
+		}
+		options.MaxDepth = p.Depth
+	}
+
+	if p.Breadth < 0 || p.Breadth > maxCrawlBreadth {
+		return options, fmt.Errorf("breadth must be between 0 and %d", maxCrawlBreadth)
+	}
+	options.MaxBreadth = p.Breadth
+
+	if p.ExcludeLowConfidence {
+		options.MinConfidence = sampled.LowConfidence
+	}
+
+	options.VerifiedOnly = p.VerifiedOnly
+
+	switch order := sampled.TraversalOrder(p.Order); order {
+	case "":
+	case sampled.DepthFirst, sampled.BreadthFirst:
+		options.Order = order
+	default:
+		return options, fmt.Errorf("unknown order: %q", order)
+	}
+
+	return options, nil
+}
+
+// parseRelationships parses relationship names from a request.
+func parseRelationships(values []string) ([]genius.RelationshipType, error) {
+	var relationships []genius.RelationshipType
+	for _, value := range values {
+		relationship, err := genius.ParseRelationshipType(value)
+		if err != nil {
+			return nil, err
+		}
+		relationships = append(relationships, relationship)
+	}
+	return relationships, nil
+}
+
+// seedResource returns the album, playlist, track or artist a submitted link, URI or ID points
+// to. Bare IDs are taken to be albums. Errors wrapping spotify.ErrInvalidLink describe what is
+// wrong with the input; others mean a short link could not be followed.
+func seedResource(ctx context.Context, value string) (spotify.Resource, error) {
+	return spotify.ResolveResource(ctx, value, spotify.AlbumResource)
+}
+
+// linkErrorStatus returns the status for a seedResource error: the input was bad, or a short link
+// could not be followed.
+func linkErrorStatus(err error) int {
+	if errors.Is(err, spotify.ErrInvalidLink) {
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
 }
@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/logger"
+)
+
+const (
+	// requestIDHeader carries the request ID, so callers can quote it when reporting a problem.
+	requestIDHeader = "X-Request-Id"
+	// cloudTraceHeader is set by Google Cloud's load balancers as TRACE_ID/SPAN_ID;o=OPTIONS.
+	cloudTraceHeader = "X-Cloud-Trace-Context"
+	// traceparentHeader is the W3C trace context header, version-traceid-parentid-flags.
+	traceparentHeader = "traceparent"
+)
+
+// withRequestLogger gives every request a logger tagged with its request ID and trace.
+func withRequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		traceID, spanID := requestTrace(r)
+		ctx := logger.With(r.Context(), logger.RequestIDKey, requestID)
+		ctx = logger.WithTrace(ctx, traceID, spanID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withSession returns the request with the session's user added to its logger.
+func withSession(r *http.Request, session *db.Session) *http.Request {
+	return r.WithContext(logger.With(r.Context(), logger.UserIDKey, session.UserID))
+}
+
+// requestTrace returns the trace and span IDs of the request, if a proxy or client sent any.
+// Span IDs are returned as 16 hex digits, as Cloud Logging expects.
+func requestTrace(r *http.Request) (string, string) {
+	if parts := strings.Split(r.Header.Get(traceparentHeader), "-"); len(parts) == 4 {
+		return parts[1], parts[2]
+	}
+
+	header := r.Header.Get(cloudTraceHeader)
+	if header == "" {
+		return "", ""
+	}
+
+	traceID, rest, _ := strings.Cut(header, "/")
+	spanID, _, _ := strings.Cut(rest, ";")
+
+	// the Cloud header carries the span ID in decimal
+	span, err := strconv.ParseUint(spanID, 10, 64)
+	if err != nil {
+		return traceID, ""
+	}
+
+	return traceID, fmt.Sprintf("%016x", span)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"sync"
+
+	"github.com/ericflores108/spotify/db"
+)
+
+// Broker fans job updates out to subscribers, such as open progress streams. Every update
+// carries the job's full state, so a slow subscriber only ever needs the latest one.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan db.Job]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[chan db.Job]struct{}),
+	}
+}
+
+// Subscribe returns a channel of updates for the job and a function that ends the subscription.
+func (b *Broker) Subscribe(jobID string) (<-chan db.Job, func()) {
+	updates := make(chan db.Job, 1)
+
+	b.mu.Lock()
+	if b.subscribers[jobID] == nil {
+		b.subscribers[jobID] = make(map[chan db.Job]struct{})
+	}
+	b.subscribers[jobID][updates] = struct{}{}
+	b.mu.Unlock()
+
+	return updates, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[jobID], updates)
+		if len(b.subscribers[jobID]) == 0 {
+			delete(b.subscribers, jobID)
+		}
+	}
+}
+
+// Publish sends the job to its subscribers without blocking, replacing any update a subscriber
+// has not read yet.
+func (b *Broker) Publish(job db.Job) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for updates := range b.subscribers[job.ID] {
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- job
+	}
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned when a task is submitted while every worker is busy and the queue
+// has no room left.
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrClosed is returned when a task is submitted after the pool was closed.
+var ErrClosed = errors.New("job pool is closed")
+
+// Pool runs submitted tasks on a fixed number of workers. Tasks wait in a bounded queue so a
+// burst of submissions cannot start unbounded work.
+type Pool struct {
+	tasks  chan func()
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPool starts workers goroutines that take tasks from a queue holding up to queueSize tasks.
+func NewPool(workers, queueSize int) *Pool {
+	p := &Pool{
+		tasks: make(chan func(), queueSize),
+	}
+
+	for range max(workers, 1) {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+
+	return p
+}
+
+// Submit queues task without blocking.
+func (p *Pool) Submit(task func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	select {
+	case p.tasks <- task:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting tasks and waits for queued and running tasks to finish.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
@@ -1,101 +1,207 @@
 package logger
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"runtime"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/logging"
 )
 
-type Logger struct {
-	mu          sync.Mutex
-	logger      *log.Logger
-	prefix      string
-	cloudLogger *logging.Logger
-}
+// Cloud Logging reads these fields from structured log entries.
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const (
+	severityKey       = "severity"
+	messageKey        = "message"
+	sourceLocationKey = "logging.googleapis.com/sourceLocation"
+	TraceKey          = "logging.googleapis.com/trace"
+	SpanIDKey         = "logging.googleapis.com/spanId"
+)
+
+// Attribute keys added to request-scoped loggers.
+const (
+	RequestIDKey = "request_id"
+	UserIDKey    = "user_id"
+	SeedKey      = "seed"
+	JobIDKey     = "job_id"
+)
 
 var (
-	InfoLogger  *Logger
-	DebugLogger *Logger
-	ErrorLogger *Logger
+	mu        sync.RWMutex
+	base      = slog.New(newHandler(os.Stdout))
+	projectID string
+	client    *logging.Client
 )
 
-// NewLogger initializes a logger
-func NewLogger(output *os.File, cloudLogger *logging.Logger) *Logger {
-	return &Logger{
-		logger:      log.New(output, "", log.Ldate|log.Ltime|log.Lshortfile),
-		cloudLogger: cloudLogger,
+type contextKey struct{}
+
+// newHandler returns a JSON handler that names the level, message and source fields the way
+// Cloud Logging expects.
+func newHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+
+			switch a.Key {
+			case slog.LevelKey:
+				a.Key = severityKey
+				if level, ok := a.Value.Any().(slog.Level); ok && level == slog.LevelWarn {
+					a.Value = slog.StringValue("WARNING")
+				}
+			case slog.MessageKey:
+				a.Key = messageKey
+			case slog.SourceKey:
+				a.Key = sourceLocationKey
+			}
+			return a
+		},
+	})
+}
+
+// cloudWriter forwards each JSON log line to Cloud Logging, lifting the severity and trace
+// fields into the entry so they are indexed like any other Cloud Logging entry.
+type cloudWriter struct {
+	logger *logging.Logger
+}
+
+func (w *cloudWriter) Write(p []byte) (int, error) {
+	line := bytes.TrimSpace(p)
+
+	var fields struct {
+		Severity string `json:"severity"`
+		Trace    string `json:"logging.googleapis.com/trace"`
+		SpanID   string `json:"logging.googleapis.com/spanId"`
 	}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return 0, err
+	}
+
+	w.logger.Log(logging.Entry{
+		Severity: logging.ParseSeverity(fields.Severity),
+		Trace:    fields.Trace,
+		SpanID:   fields.SpanID,
+		Payload:  json.RawMessage(bytes.Clone(line)),
+	})
+
+	return len(p), nil
 }
 
-// InitializeLoggers initializes loggers with Cloud Logging support
-func InitializeLoggers(ctx context.Context, projectID string) error {
-	client, err := logging.NewClient(ctx, projectID)
+// InitializeLoggers writes JSON logs to stdout and to Cloud Logging
+func InitializeLoggers(ctx context.Context, project string) error {
+	cloudClient, err := logging.NewClient(ctx, project)
 	if err != nil {
 		return fmt.Errorf("failed to create logging client: %w", err)
 	}
 
-	InfoLogger = NewLogger(os.Stdout, client.Logger("info"))
-	DebugLogger = NewLogger(os.Stdout, client.Logger("debug"))
-	ErrorLogger = NewLogger(os.Stderr, client.Logger("error"))
+	mu.Lock()
+	defer mu.Unlock()
+
+	client = cloudClient
+	projectID = project
+	base = slog.New(newHandler(io.MultiWriter(os.Stdout, &cloudWriter{logger: cloudClient.Logger("titled")})))
 	return nil
 }
 
-// SetPrefix sets a custom prefix for the logger
-func (l *Logger) SetPrefix(prefix string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.prefix = prefix
+// InitializeStdoutLoggers writes JSON logs to stdout only, for running without Google Cloud
+// credentials
+func InitializeStdoutLoggers() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	base = slog.New(newHandler(os.Stdout))
 }
 
-// ClearPrefix clears the prefix
-func (l *Logger) ClearPrefix() {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.prefix = ""
+// Close flushes buffered Cloud Logging entries.
+func Close() error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if client == nil {
+		return nil
+	}
+	return client.Close()
 }
 
-// getPrefix retrieves the prefix if set, or an empty string otherwise
-func (l *Logger) getPrefix() string {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	if l.prefix != "" {
-		return fmt.Sprintf("[%s] ", l.prefix)
+// FromContext returns the logger carried by ctx, or the process-wide logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return l
+		}
 	}
-	return ""
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return base
 }
 
-// Log writes a log entry to stdout and GCP Cloud Logging
-func (l *Logger) Log(level, msg string, args ...interface{}) {
-	formattedMsg := fmt.Sprintf(msg, args...)
-	prefixedMsg := fmt.Sprintf("%s%s", l.getPrefix(), formattedMsg)
+// With returns a context whose logger adds the given attributes, as slog key-value pairs, to
+// every entry.
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, contextKey{}, FromContext(ctx).With(args...))
+}
 
-	// Log to stdout
-	l.logger.Printf("[%s] %s", level, prefixedMsg)
+// WithTrace returns a context whose logger tags entries with the request's trace, so Cloud
+// Logging groups them under the request.
+func WithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+
+	mu.RLock()
+	trace := traceID
+	if projectID != "" {
+		trace = fmt.Sprintf("projects/%s/traces/%s", projectID, traceID)
+	}
+	mu.RUnlock()
 
-	// Log to Cloud Logging
-	if l.cloudLogger != nil {
-		l.cloudLogger.Log(logging.Entry{
-			Severity: logging.ParseSeverity(level),
-			Payload:  prefixedMsg,
-		})
+	args := []any{TraceKey, trace}
+	if spanID != "" {
+		args = append(args, SpanIDKey, spanID)
 	}
+	return With(ctx, args...)
+}
+
+// log formats the message and records it with the caller of LogInfo, LogDebug or LogError as
+// its source.
+func log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l := FromContext(ctx)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip Callers, log and the exported wrapper
+
+	record := slog.NewRecord(time.Now(), level, fmt.Sprintf(msg, args...), pcs[0])
+	_ = l.Handler().Handle(ctx, record)
 }
 
 // LogInfo logs informational messages
-func LogInfo(msg string, args ...interface{}) {
-	InfoLogger.Log("INFO", msg, args...)
+func LogInfo(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelInfo, msg, args...)
 }
 
 // LogDebug logs debug messages
-func LogDebug(msg string, args ...interface{}) {
-	DebugLogger.Log("DEBUG", msg, args...)
+func LogDebug(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelDebug, msg, args...)
 }
 
 // LogError logs error messages
-func LogError(msg string, args ...interface{}) {
-	ErrorLogger.Log("ERROR", msg, args...)
+func LogError(ctx context.Context, msg string, args ...any) {
+	log(ctx, slog.LevelError, msg, args...)
 }
@@ -6,32 +6,96 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/ericflores108/spotify/ai"
 	"github.com/ericflores108/spotify/config"
+	"github.com/ericflores108/spotify/db"
+	"github.com/ericflores108/spotify/genius"
 	"github.com/ericflores108/spotify/handlers"
 	"github.com/ericflores108/spotify/httpserver"
+	"github.com/ericflores108/spotify/jobs"
 	"github.com/ericflores108/spotify/logger"
 	"github.com/ericflores108/spotify/sampled"
+	"github.com/ericflores108/spotify/spotify"
 )
 
+// sessionSweepInterval is how often expired sessions are deleted.
+const sessionSweepInterval = time.Hour
+
+// metricsLogInterval is how often Spotify request and throttling counters are logged.
+const metricsLogInterval = 5 * time.Minute
+
+// jobSweepInterval is how often jobs lost with a stopped server are marked failed.
+const jobSweepInterval = 10 * time.Minute
+
 func main() {
 	ctx := context.Background()
 
-	err := logger.InitializeLoggers(ctx, config.GoogleProjectID)
-	if err != nil {
+	// Define a flag for the URL
+	useLocalHost := flag.Bool("useLocalHost", false, "Use localhost as the URL and run without Google Cloud (default: production URL)")
+	configFile := flag.String("configFile", "", "YAML file of secrets used with -useLocalHost, in addition to TITLED_* environment variables")
+	strictVerification := flag.Bool("strictVerification", false, "Drop AI suggestions that fail verification (default: keep them flagged as unverified)")
+	consensus := flag.Bool("consensus", false, "Query every sample source and merge their results (default: first source wins)")
+	storeBackend := flag.String("store", "", "Storage backend: firestore or bolt (default: firestore, or bolt with -useLocalHost)")
+	boltPath := flag.String("boltPath", "titled.db", "Database file used by the bolt storage backend")
+	workers := flag.Int("workers", 4, "Number of playlists generated concurrently")
+	jobQueue := flag.Int("jobQueue", 100, "Number of playlist jobs that can wait for a worker")
+	geniusBaseURL := flag.String("geniusBaseURL", genius.DefaultBaseURL, "Base URL of the Genius API, e.g. a local stand-in server")
+	geniusRate := flag.Float64("geniusRate", genius.DefaultRequestsPerSecond, "Requests per second sent to the Genius API")
+	spotifyRate := flag.Float64("spotifyRate", spotify.DefaultRequestsPerSecond, "Requests per second sent to the Spotify API, across all users")
+	flag.Parse()
+
+	if *useLocalHost {
+		logger.InitializeStdoutLoggers()
+	} else if err := logger.InitializeLoggers(ctx, config.GoogleProjectID); err != nil {
 		log.Fatalf("Failed to initialize loggers: %v", err)
 	}
+	defer logger.Close()
 
-	logger.LogInfo("starting app")
+	logger.LogInfo(ctx, "starting app")
 
-	appConfig := config.GetConfig(ctx)
-	defer appConfig.SecretManagerClient.Close()
-	defer appConfig.FirestoreClient.Close()
+	if *spotifyRate <= 0 {
+		log.Fatalf("-spotifyRate must be positive, got %v", *spotifyRate)
+	}
+	spotify.SetRateLimit(*spotifyRate)
 
-	// Define a flag for the URL
-	useLocalHost := flag.Bool("useLocalHost", false, "Use localhost as the URL (default: production URL)")
-	flag.Parse()
+	if *geniusRate <= 0 {
+		log.Fatalf("-geniusRate must be positive, got %v", *geniusRate)
+	}
+	genius.SetRateLimit(*geniusRate)
+
+	if *storeBackend == "" {
+		*storeBackend = db.FirestoreBackend
+		if *useLocalHost {
+			*storeBackend = db.BoltBackend
+		}
+	}
+
+	backend, err := db.ParseBackend(*storeBackend)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	appConfig := config.GetConfig(ctx, config.Options{
+		Local:         *useLocalHost,
+		File:          *configFile,
+		Firestore:     backend == db.FirestoreBackend,
+		GeniusBaseURL: *geniusBaseURL,
+	})
+	defer appConfig.Close()
+
+	// Initialize storage
+	var store db.Store
+	if backend == db.BoltBackend {
+		store, err = db.NewBoltStore(*boltPath)
+		if err != nil {
+			log.Fatalf("Failed to open bolt store: %v", err)
+		}
+	} else {
+		store = db.NewFirestoreStore(appConfig.FirestoreClient)
+	}
+	defer store.Close()
 
 	// Determine the URL
 	titledURL := config.ProductionURL
@@ -45,6 +109,11 @@ func main() {
 	aiService := &sampled.AIService{
 		Spotify: appConfig.SpotifyClient,
 		AI:      aiClient,
+		Verifier: &sampled.Verifier{
+			Spotify: appConfig.SpotifyClient,
+			Genius:  appConfig.GeniusClient,
+			Strict:  *strictVerification,
+		},
 	}
 
 	geniusService := &sampled.GeniusService{
@@ -53,30 +122,53 @@ func main() {
 	}
 
 	sampledManager := sampled.NewSampledManager(geniusService, aiService)
+	sampledManager.Consensus = *consensus
+	sampledManager.Cache = &db.SampleCache{Store: store}
+	// Genius relationships are curated, AI suggestions are not
+	sampledManager.Weights = map[string]float64{
+		geniusService.Name(): 1,
+		aiService.Name():     0.5,
+	}
+
+	// Playlists are generated in the background so large albums don't outlast the request
+	jobPool := jobs.NewPool(*workers, *jobQueue)
+	defer jobPool.Close()
 
 	svc := &handlers.Service{
 		SampledManager:      sampledManager,
-		Firestore:           appConfig.FirestoreClient,
+		Store:               store,
+		Jobs:                jobPool,
+		Progress:            jobs.NewBroker(),
 		SpotifyClientID:     appConfig.ClientID,
 		SpotifyClientSecret: appConfig.ClientSecret,
 		URL:                 titledURL,
 		StateKey:            config.StateKey,
 	}
 
+	// Visitors' sessions in the default account are short-lived, clear them out as they expire
+	go svc.SweepSessions(ctx, sessionSweepInterval)
+
+	// Jobs queued or running when a server stopped would otherwise never finish
+	go svc.SweepJobs(ctx, jobSweepInterval)
+
+	// /debug/vars is local only, so production sees throttling through the logs
+	go spotify.LogMetrics(ctx, metricsLogInterval)
+
 	// Initialize the server and register routes
 	srv := httpserver.NewServer(svc)
-	mux := srv.RegisterRoutes(ctx)
+	srv.Debug = *useLocalHost
+	mux := srv.RegisterRoutes()
 
 	// Determine port for HTTP service
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
-		logger.LogInfo("defaulting to port %s", port)
+		logger.LogInfo(ctx, "defaulting to port %s", port)
 	}
 
 	// Start HTTP server
-	logger.LogInfo("listening on port %s", port)
+	logger.LogInfo(ctx, "listening on port %s", port)
 	if err := http.ListenAndServe(":"+port, mux); err != nil {
-		logger.LogError("Failed to start server on port %s: %v", port, err)
+		logger.LogError(ctx, "Failed to start server on port %s: %v", port, err)
 	}
 }
@@ -0,0 +1,155 @@
+package match
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Threshold is the lowest score at which a search result is taken to be the searched song.
+const Threshold = 0.7
+
+const (
+	titleWeight  = 0.6
+	artistWeight = 0.4
+	// ContainedScore is the similarity of two names when one contains the other, e.g. an
+	// artist credited together with someone else.
+	ContainedScore = 0.8
+)
+
+var (
+	// versionWords mark a bracketed title suffix as naming a version of the song rather than
+	// being part of its title, e.g. "(feat. X)", "[Live]" or "(2011 Remaster)".
+	// Whole words only, so "(Without You)" or "(Democracy)" stay part of the title.
+	versionWords = regexp.MustCompile(`(?i)\b(remaster(ed)?|live|feat|ft|with|version|edit|mix|remix(ed)?|mono|stereo|deluxe|demo|acoustic|instrumental|anniversary|bonus|explicit|clean)\b`)
+	bracketed    = regexp.MustCompile(`\s*[(\[][^)\]]*[)\]]`)
+	featuring    = regexp.MustCompile(`(?i)\s+(feat\.?|ft\.?|featuring)\s.*$`)
+	// stripMarks removes accents, so "Beyoncé" matches "Beyonce".
+	stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+)
+
+// CleanTitle removes what Spotify and Genius add to a title to tell versions apart: dash
+// suffixes such as "- 2011 Remaster", bracketed suffixes such as "(feat. X)" or "[Live]", and
+// trailing "feat." credits.
+func CleanTitle(title string) string {
+	cleaned := bracketed.ReplaceAllStringFunc(title, func(suffix string) string {
+		if versionWords.MatchString(suffix) {
+			return ""
+		}
+		return suffix
+	})
+
+	if before, _, ok := strings.Cut(cleaned, " - "); ok {
+		cleaned = before
+	}
+	cleaned = featuring.ReplaceAllString(cleaned, "")
+
+	// a title that is nothing but a suffix is left as it was
+	if cleaned = strings.TrimSpace(cleaned); cleaned == "" {
+		return strings.TrimSpace(title)
+	}
+	return cleaned
+}
+
+// NormalizeTitle returns the title cleaned by CleanTitle and folded for comparison: lower case,
+// without accents or punctuation.
+func NormalizeTitle(title string) string {
+	return fold(CleanTitle(title))
+}
+
+// NormalizeArtist folds an artist name for comparison, dropping a leading "The".
+func NormalizeArtist(artist string) string {
+	return strings.TrimPrefix(fold(artist), "the ")
+}
+
+// fold lower-cases s, removes accents and punctuation, spells out "&" and collapses spaces.
+func fold(s string) string {
+	if stripped, _, err := transform.String(stripMarks, s); err == nil {
+		s = stripped
+	}
+	s = strings.ReplaceAll(strings.ToLower(s), "&", " and ")
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '/':
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Similarity scores two normalized names from 0 to 1: 1 when equal, ContainedScore when the
+// words of one appear in order within the other, and otherwise the share of words they have in
+// common. Only whole words count, so "nas" is not contained in "nasty c".
+func Similarity(a, b string) float64 {
+	switch {
+	case a == "" || b == "":
+		return 0
+	case a == b:
+		return 1
+	}
+
+	wordsA := strings.Fields(a)
+	wordsB := strings.Fields(b)
+	if containsWords(wordsA, wordsB) || containsWords(wordsB, wordsA) {
+		return ContainedScore
+	}
+
+	seen := make(map[string]bool, len(wordsA))
+	for _, word := range wordsA {
+		seen[word] = true
+	}
+
+	common := 0
+	for _, word := range wordsB {
+		if seen[word] {
+			common++
+			delete(seen, word)
+		}
+	}
+
+	return float64(common) / float64(len(wordsA)+len(wordsB)-common)
+}
+
+// containsWords reports whether words holds sub as a contiguous run.
+func containsWords(words, sub []string) bool {
+	if len(sub) == 0 {
+		return false
+	}
+	for i := 0; i+len(sub) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(sub)], sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// Score rates from 0 to 1 how well a result's title and artists match the searched track and
+// artist. The first of artists is the result's primary artist; an artist only found among the
+// others scores ContainedScore at most. Without a searched artist, the title decides alone.
+func Score(title string, artists []string, track, artist string) float64 {
+	titleScore := Similarity(NormalizeTitle(title), NormalizeTitle(track))
+	if artist == "" {
+		return titleScore
+	}
+
+	expected := NormalizeArtist(artist)
+	var artistScore float64
+	for i, name := range artists {
+		score := Similarity(NormalizeArtist(name), expected)
+		if i > 0 {
+			score = min(score, ContainedScore)
+		}
+		artistScore = max(artistScore, score)
+	}
+
+	return titleWeight*titleScore + artistWeight*artistScore
+}
@@ -0,0 +1,109 @@
+package match
+
+import "testing"
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Come Together - Remastered 2009", "Come Together"},
+		{"Come Together - 2019 Mix", "Come Together"},
+		{"Heroes (2017 Remaster)", "Heroes"},
+		{"Heroes (Remastered)", "Heroes"},
+		{"Say So (feat. Nicki Minaj)", "Say So"},
+		{"Say So [feat. Nicki Minaj]", "Say So"},
+		{"Say So feat. Nicki Minaj", "Say So"},
+		{"Say So ft Nicki Minaj", "Say So"},
+		{"Hurt [Live]", "Hurt"},
+		{"Hurt (Live at Wembley) (Deluxe Edition)", "Hurt"},
+		{"Intro (Radio Edit)", "Intro"},
+		{"Intro (Mono Version)", "Intro"},
+		// bracketed parts without a version word are part of the title
+		{"I Can't Live (Without You)", "I Can't Live (Without You)"},
+		{"Power (Democracy)", "Power (Democracy)"},
+		{"Interlude (Mixtape)", "Interlude (Mixtape)"},
+		{"(Don't Fear) The Reaper", "(Don't Fear) The Reaper"},
+		{"Song (Lively)", "Song (Lively)"},
+		{"Song (Edition)", "Song (Edition)"},
+		// a title that is only a suffix is kept
+		{"(Live)", "(Live)"},
+		{"  Spaced Out  ", "Spaced Out"},
+	}
+
+	for _, test := range tests {
+		if got := CleanTitle(test.title); got != test.want {
+			t.Errorf("CleanTitle(%q) = %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Déjà Vu - 2005 Remaster", "deja vu"},
+		{"Rock & Roll (Live)", "rock and roll"},
+		{"Don't Stop Me Now", "dont stop me now"},
+		{"AC/DC", "ac dc"},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeTitle(test.title); got != test.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "nas", 0},
+		{"nas", "nas", 1},
+		{"jay z", "jay z and kanye west", ContainedScore},
+		{"kanye west", "jay z and kanye west", ContainedScore},
+		{"i cant live without you", "without you", ContainedScore},
+		// containment only counts whole words
+		{"hell", "hello", 0},
+		{"nas", "nasty c", 0},
+		{"live", "i cant live without you", ContainedScore},
+		{"west kanye", "jay z and kanye west", 2.0 / 5},
+		{"hello world", "hello there", 1.0 / 3},
+		{"one two", "three four", 0},
+	}
+
+	for _, test := range tests {
+		for _, pair := range [][2]string{{test.a, test.b}, {test.b, test.a}} {
+			if got := Similarity(pair[0], pair[1]); got != test.want {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", pair[0], pair[1], got, test.want)
+			}
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		artists []string
+		track   string
+		artist  string
+		want    float64
+	}{
+		{"exact", "Heroes - 2017 Remaster", []string{"David Bowie"}, "Heroes", "David Bowie", 1},
+		{"title only", "Heroes (Live)", nil, "Heroes", "", 1},
+		{"featured artist", "Say So", []string{"Doja Cat", "Nicki Minaj"}, "Say So", "Nicki Minaj", titleWeight + artistWeight*ContainedScore},
+		{"different song", "Hello", []string{"Adele"}, "Hell", "Adele", artistWeight},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Score(test.title, test.artists, test.track, test.artist); got != test.want {
+				t.Errorf("Score(%q, %q, %q, %q) = %v, want %v", test.title, test.artists, test.track, test.artist, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter is a token bucket shared by every client of an API. It can also be paused for all of
+// them at once, when the API answers one client with a 429.
+type Limiter struct {
+	bucket *rate.Limiter
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// NewLimiter returns a Limiter that lets requestsPerSecond requests through, in bursts of up to
+// a second's worth.
+func NewLimiter(requestsPerSecond float64) *Limiter {
+	return &Limiter{
+		bucket: rate.NewLimiter(rate.Limit(requestsPerSecond), burst(requestsPerSecond)),
+	}
+}
+
+func burst(requestsPerSecond float64) int {
+	return max(1, int(requestsPerSecond))
+}
+
+// SetRate changes how many requests per second the limiter lets through.
+func (l *Limiter) SetRate(requestsPerSecond float64) {
+	l.bucket.SetLimit(rate.Limit(requestsPerSecond))
+	l.bucket.SetBurst(burst(requestsPerSecond))
+}
+
+// Wait blocks until a request may be sent, or ctx ends.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	paused := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+
+	if paused > 0 {
+		if err := Sleep(ctx, paused); err != nil {
+			return err
+		}
+	}
+
+	return l.bucket.Wait(ctx)
+}
+
+// Pause holds back every request for d.
+func (l *Limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// RetryAfter returns how long a 429 or 503 response asks clients to wait. Both seconds and HTTP
+// dates are accepted. Without the header, fallback is used.
+func RetryAfter(header http.Header, fallback time.Duration) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return fallback
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 1)) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), time.Second)
+	}
+
+	return fallback
+}
+
+// Backoff returns the wait before retry attempt+1: base doubled for each earlier attempt, capped
+// at limit, with jitter so requests that failed together don't retry together.
+func Backoff(attempt int, base, limit time.Duration) time.Duration {
+	d := min(base<<attempt, limit)
+	return d/2 + rand.N(d/2)
+}
+
+// CanWait reports whether a wait of d is no longer than limit and still leaves time for the
+// request before ctx's deadline.
+func CanWait(ctx context.Context, d, limit time.Duration) bool {
+	if d > limit {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(d).After(deadline) {
+		return false
+	}
+	return true
+}
+
+// Sleep waits for d, or until ctx ends.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
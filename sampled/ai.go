@@ -3,8 +3,10 @@ package sampled
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/ericflores108/spotify/ai"
+	"github.com/ericflores108/spotify/genius"
 	"github.com/ericflores108/spotify/logger"
 	"github.com/ericflores108/spotify/spotify"
 )
@@ -12,38 +14,66 @@ import (
 type AIService struct {
 	Spotify *spotify.AuthClient
 	AI      *ai.AIClient
+	// Verifier checks each suggestion when set. Unchecked suggestions are never marked verified.
+	Verifier *Verifier
+}
+
+func (a *AIService) Name() string {
+	return "openai"
 }
 
 func (a *AIService) GetSample(ctx context.Context, song, artist string) (*SpotifyTrack, error) {
 	aiSearch, err := a.AI.FindTrackSamples(ctx, song, artist)
 	if err != nil {
-		logger.LogError("Error occurred at AI aiSearch: %v", err)
+		logger.LogError(ctx, "Error occurred at AI aiSearch: %v", err)
 		return nil, fmt.Errorf("Could not find aiSearch: %v", err)
 	}
 
 	if aiSearch == nil {
-		logger.LogDebug("No AI sampledTrack found.")
+		logger.LogDebug(ctx, "No AI sampledTrack found.")
 		return nil, nil
 	}
 
 	spotifyTrack := &SpotifyTrack{
-		Name:   aiSearch.Name,
-		Artist: aiSearch.Artist,
+		Name:         aiSearch.Name,
+		Artist:       aiSearch.Artist,
+		Relationship: genius.Samples,
+		AIModel:      string(a.AI.ChatModel()),
 	}
 
-	// Get Spotify URI
-	trackURI, err := a.Spotify.GetTrackURI(aiSearch.Name, aiSearch.Artist)
+	// Get Spotify track
+	track, err := a.Spotify.SearchTrack(ctx, aiSearch.Name, aiSearch.Artist)
 	if err != nil {
-		logger.LogError("Error occurred at trackURI: %v", err)
+		logger.LogError(ctx, "Error occurred at SearchTrack: %v", err)
 		return nil, fmt.Errorf("Could not get trackURI: %v", err)
 	}
 
-	if trackURI == "" {
-		logger.LogDebug("No trackURI found for - TRACK - %s - ARTIST - %s", aiSearch.Name, aiSearch.Artist)
+	if track == nil || track.URI == "" {
+		logger.LogDebug(ctx, "No trackURI found for - TRACK - %s - ARTIST - %s", aiSearch.Name, aiSearch.Artist)
 		return nil, nil
 	}
 
-	spotifyTrack.URI = trackURI
+	spotifyTrack.URI = track.URI
+
+	if a.Verifier == nil {
+		return spotifyTrack, nil
+	}
+
+	// a suggestion that couldn't be checked is kept unverified, but not cached
+	verification, err := a.Verifier.Verify(ctx, song, artist, track, aiSearch.Artist)
+	if err != nil {
+		logger.LogError(ctx, "Error occurred at Verify: %v", err)
+		return spotifyTrack, fmt.Errorf("%w: failed to verify %s by %s: %w", ErrIncomplete, aiSearch.Name, aiSearch.Artist, err)
+	}
+
+	spotifyTrack.Verified = verification.Verified
+	if !verification.Verified {
+		logger.LogDebug(ctx, "Unverified AI sample %s by %s for %s: %s", aiSearch.Name, aiSearch.Artist, song, strings.Join(verification.Issues, "; "))
+	}
+
+	if verification.Rejected && a.Verifier.Strict {
+		return nil, nil
+	}
 
 	return spotifyTrack, nil
 }
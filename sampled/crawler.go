@@ -0,0 +1,174 @@
+package sampled
+
+import (
+	"context"
+
+	"github.com/ericflores108/spotify/genius"
+	"github.com/ericflores108/spotify/logger"
+)
+
+// TraversalOrder determines how a sample tree is flattened into a playlist.
+type TraversalOrder string
+
+const (
+	DepthFirst   TraversalOrder = "depth"
+	BreadthFirst TraversalOrder = "breadth"
+)
+
+// CrawlOptions controls how far the Crawler follows samples of samples.
+type CrawlOptions struct {
+	Relationships []genius.RelationshipType
+	// MaxDepth is the number of hops followed from the root track. Values below 1 are treated as 1.
+	MaxDepth int
+	// MaxBreadth caps the samples followed per track. Zero means no limit.
+	MaxBreadth int
+	Order      TraversalOrder
+	// MinConfidence excludes samples whose confidence is below it. Zero keeps every sample.
+	MinConfidence float64
+	// VerifiedOnly excludes samples that are neither curated nor verified.
+	VerifiedOnly bool
+	// Reverse follows the songs that borrowed from each track instead of the songs it borrows from.
+	Reverse bool
+}
+
+// relationships returns the relationships to look up, inverted when crawling in reverse.
+func (o CrawlOptions) relationships() []genius.RelationshipType {
+	relationships := o.Relationships
+	if len(relationships) == 0 {
+		relationships = []genius.RelationshipType{genius.Samples}
+	}
+
+	if !o.Reverse {
+		return relationships
+	}
+
+	inverse := make([]genius.RelationshipType, len(relationships))
+	for i, relationship := range relationships {
+		inverse[i] = relationship.Inverse()
+	}
+	return inverse
+}
+
+// SampleNode is a track in a sample tree together with the songs it borrows from.
+type SampleNode struct {
+	Track    *SpotifyTrack
+	Children []*SampleNode
+	// Incomplete is set on the root when a lookup in the tree failed, so the tree is missing
+	// samples and shouldn't be cached.
+	Incomplete bool
+}
+
+// Crawler builds sample trees by repeatedly asking the SampledManager for samples of samples.
+type Crawler struct {
+	Manager *SampledManager
+	Options CrawlOptions
+}
+
+func NewCrawler(manager *SampledManager, options CrawlOptions) *Crawler {
+	return &Crawler{
+		Manager: manager,
+		Options: options,
+	}
+}
+
+// Crawl builds the sample tree rooted at the given track. Tracks already present in the tree,
+// by Spotify URI or Genius song ID, are not visited again.
+func (c *Crawler) Crawl(ctx context.Context, root *SpotifyTrack) *SampleNode {
+	relationships := c.Options.relationships()
+
+	maxDepth := c.Options.MaxDepth
+	if maxDepth < 1 {
+		maxDepth = 1
+	}
+
+	visitedURIs := map[string]bool{root.URI: true}
+	visitedGeniusIDs := make(map[int]bool)
+	if root.GeniusID != 0 {
+		visitedGeniusIDs[root.GeniusID] = true
+	}
+
+	rootNode := &SampleNode{Track: root}
+	level := []*SampleNode{rootNode}
+
+	for depth := 0; depth < maxDepth && len(level) > 0; depth++ {
+		var next []*SampleNode
+
+		for _, node := range level {
+			if ctx.Err() != nil {
+				return rootNode
+			}
+
+			samples, err := c.Manager.GetTrackSamples(ctx, node.Track, relationships...)
+			if err != nil {
+				logger.LogError(ctx, "Error getting %s by %s sample: %v", node.Track.Name, node.Track.Artist, err)
+				rootNode.Incomplete = true
+			}
+
+			for _, sample := range samples {
+				if c.Options.MaxBreadth > 0 && len(node.Children) >= c.Options.MaxBreadth {
+					break
+				}
+
+				if sample.Confidence < c.Options.MinConfidence {
+					logger.LogDebug(ctx, "Skipping low confidence sample %s by %s: %.2f", sample.Name, sample.Artist, sample.Confidence)
+					continue
+				}
+
+				if c.Options.VerifiedOnly && !sample.Verified {
+					logger.LogDebug(ctx, "Skipping unverified sample %s by %s", sample.Name, sample.Artist)
+					continue
+				}
+
+				if visitedURIs[sample.URI] || (sample.GeniusID != 0 && visitedGeniusIDs[sample.GeniusID]) {
+					logger.LogDebug(ctx, "Skipping already visited sample %s by %s", sample.Name, sample.Artist)
+					continue
+				}
+
+				visitedURIs[sample.URI] = true
+				if sample.GeniusID != 0 {
+					visitedGeniusIDs[sample.GeniusID] = true
+				}
+
+				sample.Origin = root
+				child := &SampleNode{Track: sample}
+				node.Children = append(node.Children, child)
+				next = append(next, child)
+			}
+		}
+
+		level = next
+	}
+
+	return rootNode
+}
+
+// Flatten returns the Spotify URIs of the tree in the given order, starting with the root.
+func (n *SampleNode) Flatten(order TraversalOrder) []string {
+	if order == BreadthFirst {
+		var uris []string
+		queue := []*SampleNode{n}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			uris = append(uris, node.Track.URI)
+			queue = append(queue, node.Children...)
+		}
+		return uris
+	}
+
+	uris := []string{n.Track.URI}
+	for _, child := range n.Children {
+		uris = append(uris, child.Flatten(order)...)
+	}
+	return uris
+}
+
+// Samples returns every track below the root, depth-first.
+func (n *SampleNode) Samples() []*SpotifyTrack {
+	var tracks []*SpotifyTrack
+	for _, child := range n.Children {
+		tracks = append(tracks, child.Track)
+		tracks = append(tracks, child.Samples()...)
+	}
+	return tracks
+}
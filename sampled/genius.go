@@ -3,6 +3,7 @@ package sampled
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/ericflores108/spotify/genius"
@@ -15,57 +16,102 @@ type GeniusService struct {
 	Genius  *genius.GeniusClient
 }
 
+func (g *GeniusService) Name() string {
+	return "genius"
+}
+
 func (g *GeniusService) GetSample(ctx context.Context, song, artist string) (*SpotifyTrack, error) {
-	geniusSearch, err := g.Genius.Search(song, artist)
+	samples, err := g.GetSamples(ctx, song, artist)
+	if len(samples) == 0 {
+		return nil, err
+	}
+
+	return samples[0], err
+}
+
+// GetSamples returns every song Genius links to the track through one of the given
+// relationships, in the order Genius returns them. Samples are used when no relationship is
+// given. Songs that Spotify has no match for are skipped. When looking a song up on Spotify
+// fails, the songs found are returned with an error wrapping ErrIncomplete.
+func (g *GeniusService) GetSamples(ctx context.Context, song, artist string, relationships ...genius.RelationshipType) ([]*SpotifyTrack, error) {
+	if len(relationships) == 0 {
+		relationships = []genius.RelationshipType{genius.Samples}
+	}
+
+	geniusSearch, err := g.Genius.Search(ctx, song, artist)
 	if err != nil {
 		return nil, fmt.Errorf("Could not search Genius: %v", err)
 	}
 
 	if len(geniusSearch.Response.Hits) == 0 {
-		logger.LogDebug("Genius search has no hits")
+		logger.LogDebug(ctx, "Genius search has no hits")
+		return nil, nil
+	}
+
+	hit, score, ok := geniusSearch.BestMatch(song, artist)
+	if !ok {
+		logger.LogDebug(ctx, "No Genius hit matches %s by %s, best was %q scoring %.2f", song, artist, hit.Result.FullTitle, score)
 		return nil, nil
 	}
 
-	geniusSong, err := g.Genius.Songs(strconv.Itoa(geniusSearch.Response.Hits[0].Result.ID))
+	geniusSong, err := g.Genius.Songs(ctx, strconv.Itoa(hit.Result.ID))
 	if err != nil {
 		return nil, fmt.Errorf("Could not get Genius song: %v", err)
 	}
 
 	if len(geniusSong.Response.Song.SongRelationships) == 0 {
-		logger.LogDebug("Song has no song relationships")
+		logger.LogDebug(ctx, "Song has no song relationships")
 		return nil, nil
 	}
 
-	var spotifyTrack *SpotifyTrack
+	var (
+		samples []*SpotifyTrack
+		failed  int
+		lastErr error
+	)
 
 	for _, relation := range geniusSong.Response.Song.SongRelationships {
-		if relation.RelationshipType == "samples" && len(relation.Songs) > 0 {
-			spotifyTrack = &SpotifyTrack{
-				Artist: relation.Songs[0].Artist,
-				Name:   relation.Songs[0].Title,
-			}
-			break
+		if !slices.Contains(relationships, relation.RelationshipType) {
+			continue
 		}
-	}
 
-	if spotifyTrack == nil {
-		logger.LogDebug("Song has no Genius samples")
-		return nil, nil
+		for _, sample := range relation.Songs {
+			spotifyTrack := &SpotifyTrack{
+				Artist:       sample.Artist,
+				Name:         sample.Title,
+				Relationship: relation.RelationshipType,
+				GeniusID:     sample.ID,
+				GeniusURL:    sample.URL,
+				Verified:     true,
+			}
+
+			// Get Spotify URI
+			trackURI, err := g.Spotify.GetTrackURI(ctx, spotifyTrack.Name, spotifyTrack.Artist)
+			if err != nil {
+				logger.LogError(ctx, "Error occurred at trackURI: %v", err)
+				failed++
+				lastErr = err
+				continue
+			}
+
+			if trackURI == "" {
+				logger.LogDebug(ctx, "No trackURI found for - TRACK - %s - ARTIST - %s", spotifyTrack.Name, spotifyTrack.Artist)
+				continue
+			}
+
+			spotifyTrack.URI = trackURI
+			samples = append(samples, spotifyTrack)
+		}
 	}
 
-	// Get Spotify URI
-	trackURI, err := g.Spotify.GetTrackURI(spotifyTrack.Name, spotifyTrack.Artist)
-	if err != nil {
-		logger.LogError("Error occurred at trackURI: %v", err)
-		return nil, fmt.Errorf("Could not get trackURI: %v", err)
+	if failed > 0 {
+		return samples, fmt.Errorf("%w: %d songs related to %s by %s could not be looked up on Spotify: %w", ErrIncomplete, failed, song, artist, lastErr)
 	}
 
-	if trackURI == "" {
-		logger.LogDebug("No trackURI found for - TRACK - %s - ARTIST - %s", spotifyTrack.Name, spotifyTrack.Artist)
+	if len(samples) == 0 {
+		logger.LogDebug(ctx, "Song has no Genius samples")
 		return nil, nil
 	}
 
-	spotifyTrack.URI = trackURI
-
-	return spotifyTrack, nil
+	return samples, nil
 }
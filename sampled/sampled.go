@@ -1,19 +1,81 @@
 package sampled
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ericflores108/spotify/genius"
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/match"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+// LowConfidence is the confidence below which a sample is considered a weak pick.
+const LowConfidence = 0.5
+
+// ErrIncomplete is returned, wrapped and together with the samples that were found, when some
+// samples could not be looked up, e.g. because Spotify failed to resolve them. Such results are
+// used but never cached.
+var ErrIncomplete = errors.New("incomplete samples")
 
 type SpotifyTrack struct {
-	Name   string
-	Artist string
-	URI    string
+	Name         string
+	Artist       string
+	URI          string
+	Relationship genius.RelationshipType
+	GeniusID     int
+	// Confidence is the weighted share of the manager's sources that returned this track, from 0
+	// to 1, and at least LowConfidence when the track is Verified.
+	Confidence float64
+	// Sources names every source that returned this track.
+	Sources []string
+	// GeniusURL is the Genius page of the song, when Genius found it.
+	GeniusURL string
+	// AIModel is the model that suggested the song, when an AI source found it.
+	AIModel string
+	// Verified is true when the song comes from curated data or passed verification.
+	Verified bool
+	// Origin is the album track whose sample tree this track was found in.
+	Origin *SpotifyTrack
 }
 
 type Sampled interface {
 	GetSample(ctx context.Context, song, artist string) (*SpotifyTrack, error)
 }
 
+// MultiSampled is implemented by sources that can return every song a track borrows from,
+// not just the first one. Only relationships of the given types are returned; when none are
+// given, only samples are returned.
+type MultiSampled interface {
+	GetSamples(ctx context.Context, song, artist string, relationships ...genius.RelationshipType) ([]*SpotifyTrack, error)
+}
+
+// SampleCache stores the samples found for a track so repeat lookups skip the sources.
+// A cached empty result means no sample was found.
+type SampleCache interface {
+	GetSamples(ctx context.Context, key string) (samples []*SpotifyTrack, found bool, err error)
+	SetSamples(ctx context.Context, key string, samples []*SpotifyTrack) error
+}
+
+// Named is implemented by sources that report a name for confidence weights and results.
+type Named interface {
+	Name() string
+}
+
 type SampledManager struct {
 	Sources []Sampled
+	// Consensus queries every source concurrently and merges their results instead of
+	// stopping at the first source that finds a sample.
+	Consensus bool
+	// Weights holds how much each source, by name, counts towards a track's confidence.
+	// Sources without a weight count as 1.
+	Weights map[string]float64
+	// Cache is consulted before any source when set.
+	Cache SampleCache
 }
 
 func NewSampledManager(sources ...Sampled) *SampledManager {
@@ -21,3 +83,244 @@ func NewSampledManager(sources ...Sampled) *SampledManager {
 		Sources: sources,
 	}
 }
+
+// sourceName returns the name a source reports, or its type name when it does not implement Named.
+func sourceName(source Sampled) string {
+	if named, ok := source.(Named); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", source)
+}
+
+func (m *SampledManager) weight(name string) float64 {
+	if weight, ok := m.Weights[name]; ok {
+		return weight
+	}
+	return 1
+}
+
+func (m *SampledManager) totalWeight() float64 {
+	var total float64
+	for _, source := range m.Sources {
+		total += m.weight(sourceName(source))
+	}
+	return total
+}
+
+// GetSamples returns the samples for a track, consulting the cache before any source.
+// It is equivalent to GetTrackSamples for a track without a Spotify URI.
+func (m *SampledManager) GetSamples(ctx context.Context, song, artist string, relationships ...genius.RelationshipType) ([]*SpotifyTrack, error) {
+	return m.GetTrackSamples(ctx, &SpotifyTrack{Name: song, Artist: artist}, relationships...)
+}
+
+// GetTrackSamples returns the samples for a track. The cache is checked by Spotify URI, then
+// by normalized title and artist, and both keys are filled after a lookup.
+func (m *SampledManager) GetTrackSamples(ctx context.Context, track *SpotifyTrack, relationships ...genius.RelationshipType) ([]*SpotifyTrack, error) {
+	if len(relationships) == 0 {
+		relationships = []genius.RelationshipType{genius.Samples}
+	}
+
+	if m.Cache == nil {
+		return m.lookupSamples(ctx, track.Name, track.Artist, relationships)
+	}
+
+	keys := m.cacheKeys(ctx, track, relationships)
+	for _, key := range keys {
+		samples, found, err := m.Cache.GetSamples(ctx, key)
+		if err != nil {
+			logger.LogError(ctx, "Error reading sample cache for %s: %v", key, err)
+			continue
+		}
+		if found {
+			logger.LogDebug(ctx, "Sample cache hit for %s", key)
+			return samples, nil
+		}
+	}
+
+	samples, err := m.lookupSamples(ctx, track.Name, track.Artist, relationships)
+	if err == nil {
+		// sources skip lookups that fail, so a cancelled lookup can look like an empty one
+		err = ctx.Err()
+	}
+	if err != nil {
+		// don't cache failures as a missing sample, or incomplete results as all there is
+		return samples, err
+	}
+
+	for _, key := range keys {
+		if err := m.Cache.SetSamples(ctx, key, samples); err != nil {
+			logger.LogError(ctx, "Error writing sample cache for %s: %v", key, err)
+		}
+	}
+
+	return samples, nil
+}
+
+// cacheKeys returns the cache keys for a track, most specific first. The name key uses the
+// normalized title and artist, so versions such as "Song (2011 Remaster)" and "Song (feat. X)"
+// share one entry.
+func (m *SampledManager) cacheKeys(ctx context.Context, track *SpotifyTrack, relationships []genius.RelationshipType) []string {
+	names := make([]string, len(relationships))
+	for i, relationship := range relationships {
+		names[i] = string(relationship)
+	}
+	slices.Sort(names)
+
+	suffix := strings.Join(slices.Compact(names), ",")
+	if m.Consensus {
+		suffix += "|consensus"
+	}
+	// samples are resolved to tracks playable in the market
+	if market := spotify.MarketFromContext(ctx); market != "" {
+		suffix += "|" + market
+	}
+
+	var keys []string
+	if track.URI != "" {
+		keys = append(keys, fmt.Sprintf("uri:%s|%s", track.URI, suffix))
+	}
+	keys = append(keys, fmt.Sprintf("name:%s|%s|%s", match.NormalizeTitle(track.Name), match.NormalizeArtist(track.Artist), suffix))
+	return keys
+}
+
+// lookupSamples asks the sources for samples. By default the sources are walked in priority
+// order and the samples from the first source that finds any are returned; in consensus mode
+// every source is queried and their results are merged. Sources that only implement Sampled
+// contribute at most one track, and only when samples are among the requested relationships.
+func (m *SampledManager) lookupSamples(ctx context.Context, song, artist string, relationships []genius.RelationshipType) ([]*SpotifyTrack, error) {
+	if m.Consensus {
+		return m.consensusSamples(ctx, song, artist, relationships)
+	}
+
+	var (
+		lastErr error
+		// incomplete is kept so a later source's samples aren't cached in place of the ones an
+		// earlier source failed to find
+		incomplete error
+	)
+
+	for _, source := range m.Sources {
+		tracks, err := m.sourceSamples(ctx, source, song, artist, relationships)
+		if errors.Is(err, ErrIncomplete) {
+			incomplete = err
+		}
+		if len(tracks) > 0 {
+			return tracks, incomplete
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	return nil, lastErr
+}
+
+// consensusSamples queries every source concurrently and merges the candidates by Spotify URI.
+// Candidates keep the order of the highest priority source that returned them, and add up the
+// confidence of each source that agrees on them, to at most 1. When any source failed, merged
+// candidates are returned with an error wrapping ErrIncomplete.
+func (m *SampledManager) consensusSamples(ctx context.Context, song, artist string, relationships []genius.RelationshipType) ([]*SpotifyTrack, error) {
+	var (
+		results = make([][]*SpotifyTrack, len(m.Sources))
+		errs    = make([]error, len(m.Sources))
+		wg      sync.WaitGroup
+	)
+
+	for index, source := range m.Sources {
+		wg.Add(1)
+		go func(index int, source Sampled) {
+			defer wg.Done()
+			results[index], errs[index] = m.sourceSamples(ctx, source, song, artist, relationships)
+		}(index, source)
+	}
+
+	wg.Wait()
+
+	var (
+		merged  []*SpotifyTrack
+		byURI   = make(map[string]*SpotifyTrack)
+		lastErr error
+	)
+
+	for index, tracks := range results {
+		if errs[index] != nil {
+			lastErr = errs[index]
+		}
+
+		for _, track := range tracks {
+			existing, ok := byURI[track.URI]
+			if !ok {
+				byURI[track.URI] = track
+				merged = append(merged, track)
+				continue
+			}
+
+			// a source that returned the same track twice only counts once
+			if len(track.Sources) > 0 && !slices.Contains(existing.Sources, track.Sources[0]) {
+				existing.Sources = append(existing.Sources, track.Sources[0])
+				existing.Confidence = min(existing.Confidence+track.Confidence, 1)
+			}
+			if existing.GeniusID == 0 {
+				existing.GeniusID = track.GeniusID
+				existing.GeniusURL = track.GeniusURL
+			}
+			if existing.AIModel == "" {
+				existing.AIModel = track.AIModel
+			}
+			existing.Verified = existing.Verified || track.Verified
+		}
+	}
+
+	if len(merged) == 0 {
+		return nil, lastErr
+	}
+
+	if lastErr != nil && !errors.Is(lastErr, ErrIncomplete) {
+		lastErr = fmt.Errorf("%w: %w", ErrIncomplete, lastErr)
+	}
+
+	return merged, lastErr
+}
+
+// sourceSamples asks a single source for samples and marks each result with the source's
+// name and its share of the confidence. Samples returned with an ErrIncomplete error are kept.
+func (m *SampledManager) sourceSamples(ctx context.Context, source Sampled, song, artist string, relationships []genius.RelationshipType) ([]*SpotifyTrack, error) {
+	var (
+		tracks []*SpotifyTrack
+		err    error
+	)
+
+	if multi, ok := source.(MultiSampled); ok {
+		tracks, err = multi.GetSamples(ctx, song, artist, relationships...)
+	} else if slices.Contains(relationships, genius.Samples) {
+		var track *SpotifyTrack
+		track, err = source.GetSample(ctx, song, artist)
+		if track != nil {
+			if track.Relationship == "" {
+				track.Relationship = genius.Samples
+			}
+			tracks = []*SpotifyTrack{track}
+		}
+	}
+
+	if err != nil && !errors.Is(err, ErrIncomplete) {
+		return nil, err
+	}
+
+	name := sourceName(source)
+	confidence := 0.0
+	if total := m.totalWeight(); total > 0 {
+		confidence = m.weight(name) / total
+	}
+
+	for _, track := range tracks {
+		track.Sources = []string{name}
+		track.Confidence = confidence
+		// a pick that was checked isn't a weak one, even from a source that counts for little
+		if track.Verified {
+			track.Confidence = max(confidence, LowConfidence)
+		}
+	}
+
+	return tracks, err
+}
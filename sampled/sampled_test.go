@@ -0,0 +1,64 @@
+package sampled
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ericflores108/spotify/genius"
+)
+
+// fakeSource returns the same track for every song.
+type fakeSource struct {
+	name  string
+	track SpotifyTrack
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) GetSample(ctx context.Context, song, artist string) (*SpotifyTrack, error) {
+	track := f.track
+	return &track, nil
+}
+
+func TestSourceConfidence(t *testing.T) {
+	geniusSource := &fakeSource{name: "genius", track: SpotifyTrack{URI: "spotify:track:genius", Verified: true}}
+	verifiedAI := &fakeSource{name: "openai", track: SpotifyTrack{URI: "spotify:track:ai", Verified: true}}
+	unverifiedAI := &fakeSource{name: "openai", track: SpotifyTrack{URI: "spotify:track:ai"}}
+	weights := map[string]float64{"openai": 0.5}
+
+	tests := []struct {
+		name      string
+		sources   []Sampled
+		consensus bool
+		want      float64
+		low       bool
+	}{
+		{"lone Genius pick", []Sampled{geniusSource, unverifiedAI}, false, 1 / 1.5, false},
+		{"verified AI pick", []Sampled{verifiedAI, geniusSource}, false, LowConfidence, false},
+		{"unverified AI pick", []Sampled{unverifiedAI, geniusSource}, false, 0.5 / 1.5, true},
+		{"verified AI pick alone", []Sampled{verifiedAI}, false, 1, false},
+		{"Genius and AI agree", []Sampled{geniusSource, &fakeSource{name: "openai", track: geniusSource.track}}, true, 1, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := &SampledManager{Sources: test.sources, Weights: weights, Consensus: test.consensus}
+
+			samples, err := manager.lookupSamples(context.Background(), "song", "artist", []genius.RelationshipType{genius.Samples})
+			if err != nil {
+				t.Fatalf("lookupSamples error = %v", err)
+			}
+			if len(samples) != 1 {
+				t.Fatalf("lookupSamples returned %d samples, want 1", len(samples))
+			}
+
+			got := samples[0].Confidence
+			if got != test.want {
+				t.Errorf("confidence = %v, want %v", got, test.want)
+			}
+			if low := got < LowConfidence; low != test.low {
+				t.Errorf("low confidence = %v, want %v", low, test.low)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package sampled
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/ericflores108/spotify/genius"
+	"github.com/ericflores108/spotify/match"
+	"github.com/ericflores108/spotify/spotify"
+)
+
+// Verifier cross-checks AI suggestions against Spotify and, when configured, Genius metadata.
+type Verifier struct {
+	Spotify *spotify.AuthClient
+	// Genius is optional. When set, a suggestion is only verified if Genius also lists it.
+	Genius *genius.GeniusClient
+	// Strict rejects suggestions that fail a check instead of flagging them as unverified.
+	Strict bool
+}
+
+// Verification is the outcome of checking a suggestion.
+type Verification struct {
+	// Verified is true when every check passed.
+	Verified bool
+	// Rejected is true when a check proved the suggestion wrong, such as a sample released
+	// after the song that samples it.
+	Rejected bool
+	Issues   []string
+}
+
+// Verify checks the suggested sample, as resolved on Spotify, against the song that samples it.
+func (v *Verifier) Verify(ctx context.Context, song, artist string, suggestion *spotify.Track, suggestedArtist string) (*Verification, error) {
+	verification := &Verification{Verified: true}
+
+	fail := func(rejected bool, format string, args ...any) {
+		verification.Verified = false
+		verification.Rejected = verification.Rejected || rejected
+		verification.Issues = append(verification.Issues, fmt.Sprintf(format, args...))
+	}
+
+	// The Spotify result must be by the suggested artist, otherwise the search found another song
+	if !artistMatches(suggestion.Artists, suggestedArtist) {
+		fail(true, "spotify result %s is not by %s", suggestion.Name, suggestedArtist)
+	}
+
+	// A sample has to be released before the song that samples it
+	sampling, err := v.Spotify.SearchTrack(ctx, song, artist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sampling track: %w", err)
+	}
+
+	sampleYear, sampleOK := releaseYear(suggestion.Album.ReleaseDate)
+	samplingYear, samplingOK := 0, false
+	if sampling != nil {
+		samplingYear, samplingOK = releaseYear(sampling.Album.ReleaseDate)
+	}
+
+	switch {
+	case !sampleOK || !samplingOK:
+		fail(false, "release years unknown for %s or %s", suggestion.Name, song)
+	case sampleYear > samplingYear:
+		fail(true, "%s (%d) was released after %s (%d)", suggestion.Name, sampleYear, song, samplingYear)
+	}
+
+	if v.Genius != nil {
+		confirmed, err := v.confirmedByGenius(ctx, song, artist, suggestion.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to confirm with Genius: %w", err)
+		}
+		if !confirmed {
+			fail(false, "Genius does not list %s as borrowed by %s", suggestion.Name, song)
+		}
+	}
+
+	return verification, nil
+}
+
+// confirmedByGenius reports whether Genius lists the suggested title among the songs the track borrows from.
+func (v *Verifier) confirmedByGenius(ctx context.Context, song, artist, suggestedTitle string) (bool, error) {
+	search, err := v.Genius.Search(ctx, song, artist)
+	if err != nil {
+		return false, err
+	}
+
+	hit, _, ok := search.BestMatch(song, artist)
+	if !ok {
+		return false, nil
+	}
+
+	geniusSong, err := v.Genius.Songs(ctx, strconv.Itoa(hit.Result.ID))
+	if err != nil {
+		return false, err
+	}
+
+	for _, relation := range geniusSong.Response.Song.SongRelationships {
+		if !slices.Contains(genius.BorrowedRelationships, relation.RelationshipType) {
+			continue
+		}
+		for _, related := range relation.Songs {
+			if match.NormalizeTitle(related.Title) == match.NormalizeTitle(suggestedTitle) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// artistMatches reports whether any of the artists matches the expected name, compared as
+// normalized names by match.Similarity. One name may contain the other, but only as whole
+// words, so "Jay-Z" matches "JAY-Z & Kanye West" while "Eve" doesn't match "Steve Miller Band".
+func artistMatches(artists []spotify.Artist, expected string) bool {
+	expected = match.NormalizeArtist(expected)
+	for _, artist := range artists {
+		if match.Similarity(match.NormalizeArtist(artist.Name), expected) >= match.ContainedScore {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseYear returns the year of a Spotify release date, which may be a year, year-month or full date.
+func releaseYear(releaseDate string) (int, bool) {
+	if len(releaseDate) < 4 {
+		return 0, false
+	}
+	year, err := strconv.Atoi(releaseDate[:4])
+	if err != nil {
+		return 0, false
+	}
+	return year, true
+}
@@ -0,0 +1,39 @@
+package sampled
+
+import (
+	"testing"
+
+	"github.com/ericflores108/spotify/spotify"
+)
+
+func TestArtistMatches(t *testing.T) {
+	tests := []struct {
+		artists  []string
+		expected string
+		want     bool
+	}{
+		{[]string{"Eve"}, "Eve", true},
+		{[]string{"The Beatles"}, "beatles", true},
+		{[]string{"Beyoncé"}, "Beyonce", true},
+		{[]string{"JAY-Z & Kanye West"}, "Jay-Z", true},
+		{[]string{"Daft Punk", "Pharrell Williams"}, "Pharrell Williams", true},
+		{[]string{"Steve Miller Band"}, "Eve", false},
+		{[]string{"Tiësto"}, "T.I.", false},
+		{[]string{"Tiësto"}, "ti", false},
+		{[]string{"Nasty C"}, "Nas", false},
+		{[]string{""}, "Eve", false},
+		{[]string{"Eve"}, "", false},
+		{nil, "Eve", false},
+	}
+
+	for _, test := range tests {
+		artists := make([]spotify.Artist, len(test.artists))
+		for i, name := range test.artists {
+			artists[i] = spotify.Artist{Name: name}
+		}
+
+		if got := artistMatches(artists, test.expected); got != test.want {
+			t.Errorf("artistMatches(%q, %q) = %v, want %v", test.artists, test.expected, got, test.want)
+		}
+	}
+}
@@ -1,23 +1,17 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 )
 
-func (c *AuthClient) GetAlbum(albumID string) (*Album, error) {
+func (c *AuthClient) GetAlbum(ctx context.Context, albumID string) (*Album, error) {
 	url := fmt.Sprintf("/albums/%s", albumID)
 
-	req, err := http.NewRequest("GET", BaseURL+url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
-
-	resp, err := c.Client.Do(req)
+	resp, err := c.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -37,36 +31,12 @@ func (c *AuthClient) GetAlbum(albumID string) (*Album, error) {
 	return &albumResponse, nil
 }
 
-func (c *AuthClient) GetAlbumTracks(albumID string) (*AlbumResponse, error) {
-	// Construct the URL for the album endpoint
-	url := fmt.Sprintf("/albums/%s/tracks", albumID)
-
-	// Create the GET request
-	req, err := http.NewRequest("GET", BaseURL+url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Add Authorization header
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
-
-	// Send the request
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read and parse the response
-	body, err := io.ReadAll(resp.Body)
+// GetAlbumTracks returns every track of the album, reading as many pages as it takes.
+func (c *AuthClient) GetAlbumTracks(ctx context.Context, albumID string) ([]TrackDetails, error) {
+	tracks, err := collect[TrackDetails](ctx, c, fmt.Sprintf("/albums/%s/tracks", albumID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to get album tracks: %w", err)
 	}
 
-	var albumResponse AlbumResponse
-	if err := json.Unmarshal(body, &albumResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	return &albumResponse, nil
+	return tracks, nil
 }
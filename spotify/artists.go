@@ -1,19 +1,30 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
+	"strings"
+)
+
+// Album groups, which say how an artist's release relates to them. See GetArtistAlbums.
+const (
+	AlbumGroup       = "album"
+	SingleGroup      = "single"
+	AppearsOnGroup   = "appears_on"
+	CompilationGroup = "compilation"
 )
 
 // GetArtist retrieves artist information by artist ID.
 // It makes an authenticated request to the "artists/{id}" endpoint and returns a pointer to ArtistResponse or an error.
-func (c *AuthClient) GetArtist(artistID string) (*ArtistResponse, error) {
+func (c *AuthClient) GetArtist(ctx context.Context, artistID string) (*ArtistResponse, error) {
 	// Build the endpoint with the artist ID
 	endpoint := fmt.Sprintf("/artists/%s", artistID)
 
 	// Make the GET request using the AuthClient's Get method
-	resp, err := c.Get(endpoint)
+	resp, err := c.Get(ctx, endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get artist info: %w", err)
 	}
@@ -35,3 +46,28 @@ func (c *AuthClient) GetArtist(artistID string) (*ArtistResponse, error) {
 	// Return the parsed response and nil error on success
 	return &artistResponse, nil
 }
+
+// GetArtistAlbums returns the artist's releases in the given album groups, or in every group
+// when none are given, reading as many pages as it takes. Under a context from WithMarket, only
+// releases available in that market are returned.
+func (c *AuthClient) GetArtistAlbums(ctx context.Context, artistID string, groups ...string) ([]Album, error) {
+	query := url.Values{}
+	if len(groups) > 0 {
+		query.Set("include_groups", strings.Join(groups, ","))
+	}
+	if market := MarketFromContext(ctx); market != "" {
+		query.Set("market", market)
+	}
+
+	endpoint := fmt.Sprintf("/artists/%s/albums", artistID)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	albums, err := collect[Album](ctx, c, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artist albums: %w", err)
+	}
+
+	return albums, nil
+}
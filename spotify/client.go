@@ -2,6 +2,7 @@ package spotify
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -19,12 +20,12 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-func NewSpotifyClient(clientID, clientSecret string) (*AuthClient, error) {
+func NewSpotifyClient(ctx context.Context, clientID, clientSecret string) (*AuthClient, error) {
 	auth := base64.StdEncoding.EncodeToString([]byte(clientID + ":" + clientSecret))
 	url := "https://accounts.spotify.com/api/token"
 	data := []byte(`grant_type=client_credentials`)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -59,7 +60,19 @@ func NewSpotifyClient(clientID, clientSecret string) (*AuthClient, error) {
 	}, nil
 }
 
-func NewSpotifyUserClient(refreshToken, clientID, clientSecret string) (string, error) {
+// NewSpotifyUserClient exchanges a user's refresh token for a new access token.
+func NewSpotifyUserClient(ctx context.Context, refreshToken, clientID, clientSecret string) (string, error) {
+	token, err := RefreshToken(ctx, refreshToken, clientID, clientSecret)
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
+
+// RefreshToken exchanges a user's refresh token for a new token. The response only carries a
+// refresh token when Spotify rotated it.
+func RefreshToken(ctx context.Context, refreshToken, clientID, clientSecret string) (*TokenResponse, error) {
 	// Spotify token endpoint
 	tokenURL := "https://accounts.spotify.com/api/token"
 
@@ -72,9 +85,9 @@ func NewSpotifyUserClient(refreshToken, clientID, clientSecret string) (string,
 	authHeader := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", clientID, clientSecret)))
 
 	// Create a new HTTP POST request
-	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
@@ -85,30 +98,30 @@ func NewSpotifyUserClient(refreshToken, clientID, clientSecret string) (string,
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check for non-200 status code
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New("failed to refresh access token, status code: " + resp.Status)
+		return nil, errors.New("failed to refresh access token, status code: " + resp.Status)
 	}
 
 	// Read and parse the response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Parse the access token from the response
 	var token TokenResponse
 	if err := json.Unmarshal(body, &token); err != nil {
-		return "", fmt.Errorf("failed to parse token response: %w", err)
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
 	}
 
 	if token.AccessToken == "" {
-		return "", errors.New("access token not found in response")
+		return nil, errors.New("access token not found in response")
 	}
 
-	return token.AccessToken, nil
+	return &token, nil
 }
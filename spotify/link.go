@@ -0,0 +1,210 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResourceType is the kind of Spotify object a link points to.
+type ResourceType string
+
+const (
+	AlbumResource    ResourceType = "album"
+	TrackResource    ResourceType = "track"
+	PlaylistResource ResourceType = "playlist"
+	ArtistResource   ResourceType = "artist"
+)
+
+var resourceTypes = []ResourceType{AlbumResource, TrackResource, PlaylistResource, ArtistResource}
+
+// Resource references a Spotify album, track, playlist or artist.
+type Resource struct {
+	Type ResourceType
+	ID   string
+}
+
+// URI returns the resource's Spotify URI, e.g. spotify:album:4aawyAB9vmqN3uQ7FjRGTy.
+func (r Resource) URI() string {
+	return fmt.Sprintf("spotify:%s:%s", r.Type, r.ID)
+}
+
+var (
+	// ErrInvalidLink is returned, wrapped with the reason, for input that isn't a Spotify link,
+	// URI or ID.
+	ErrInvalidLink = errors.New("invalid Spotify link")
+	// ErrShortLink is returned by ParseResource for spotify.link short links, which only
+	// ResolveResource can follow.
+	ErrShortLink = errors.New("short links must be resolved")
+)
+
+// idPattern matches Spotify IDs: 22 base-62 characters.
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+// ValidID reports whether id is a well-formed Spotify ID, safe to put in an API path.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+const (
+	openHost = "open.spotify.com"
+	// maxShortLinkRedirects bounds how many redirects a short link may take to open.spotify.com.
+	maxShortLinkRedirects = 5
+	// shortLinkTimeout bounds resolving a short link.
+	shortLinkTimeout = 10 * time.Second
+)
+
+// shortLinkHosts serve short links that redirect to open.spotify.com, possibly through each other.
+var shortLinkHosts = []string{"spotify.link", "spotify.app.link", "spotify-alternate.app.link"}
+
+// shortLinkTransport sends short link requests, http.DefaultTransport when nil.
+var shortLinkTransport http.RoundTripper
+
+// ParseResource parses any of the forms Spotify shares objects in:
+//
+//   - open.spotify.com URLs, with or without a scheme, locale prefix (/intl-de/) or query string
+//   - embed and legacy user playlist URLs
+//   - URIs such as spotify:album:ID
+//   - bare IDs, which are taken to be of type bare
+//
+// spotify.link short links return ErrShortLink, see ResolveResource.
+func ParseResource(input string, bare ResourceType) (Resource, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return Resource{}, fmt.Errorf("%w: no link given", ErrInvalidLink)
+	}
+
+	if ValidID(input) {
+		return Resource{Type: bare, ID: input}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(input, "spotify:"); ok {
+		return parseParts(strings.Split(rest, ":"), input)
+	}
+
+	// links are often pasted without their scheme
+	link := input
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return Resource{}, fmt.Errorf("%w: %q is not a link, URI or ID", ErrInvalidLink, input)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch {
+	case slices.Contains(shortLinkHosts, host):
+		return Resource{}, ErrShortLink
+	case host != openHost && host != "play.spotify.com":
+		return Resource{}, fmt.Errorf("%w: %s is not a Spotify address", ErrInvalidLink, u.Host)
+	}
+
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	// drop the locale, e.g. /intl-de/album/ID, and embed prefixes
+	if len(segments) > 0 && strings.HasPrefix(segments[0], "intl-") {
+		segments = segments[1:]
+	}
+	if len(segments) > 0 && (segments[0] == "embed" || segments[0] == "embed-podcast") {
+		segments = segments[1:]
+	}
+
+	return parseParts(segments, u.String())
+}
+
+// parseParts reads a type and ID from the segments of a URL path or URI, taking the last pair
+// so legacy user/NAME/playlist/ID forms work too.
+func parseParts(parts []string, input string) (Resource, error) {
+	for i := len(parts) - 2; i >= 0; i-- {
+		resourceType := ResourceType(parts[i])
+		if !slices.Contains(resourceTypes, resourceType) {
+			continue
+		}
+
+		id := parts[i+1]
+		if !ValidID(id) {
+			return Resource{}, fmt.Errorf("%w: %q is not a valid %s ID", ErrInvalidLink, id, resourceType)
+		}
+
+		return Resource{Type: resourceType, ID: id}, nil
+	}
+
+	return Resource{}, fmt.Errorf("%w: %s does not point to an album, track, playlist or artist", ErrInvalidLink, input)
+}
+
+// ResolveResource parses input like ParseResource, first following spotify.link short links to
+// the open.spotify.com link they redirect to.
+func ResolveResource(ctx context.Context, input string, bare ResourceType) (Resource, error) {
+	resource, err := ParseResource(input, bare)
+	if !errors.Is(err, ErrShortLink) {
+		return resource, err
+	}
+
+	target, err := followShortLink(ctx, strings.TrimSpace(input))
+	if err != nil {
+		return Resource{}, err
+	}
+
+	resource, err = ParseResource(target, bare)
+	if errors.Is(err, ErrShortLink) {
+		return Resource{}, fmt.Errorf("%w: short link %s did not lead to open.spotify.com", ErrInvalidLink, input)
+	}
+	return resource, err
+}
+
+// followShortLink returns the open.spotify.com URL a short link redirects to. Redirects are
+// only followed between short link hosts, so a short link can't send the server elsewhere.
+func followShortLink(ctx context.Context, link string) (string, error) {
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, shortLinkTimeout)
+	defer cancel()
+
+	var target string
+	client := &http.Client{
+		Transport: shortLinkTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			host := strings.ToLower(req.URL.Hostname())
+			switch {
+			case host == openHost:
+				target = req.URL.String()
+				return http.ErrUseLastResponse
+			case len(via) >= maxShortLinkRedirects:
+				return fmt.Errorf("%w: short link redirected too many times", ErrInvalidLink)
+			case !slices.Contains(shortLinkHosts, host):
+				return fmt.Errorf("%w: short link redirected to %s", ErrInvalidLink, host)
+			}
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not a link", ErrInvalidLink, link)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && errors.Is(urlErr.Err, ErrInvalidLink) {
+			return "", urlErr.Err
+		}
+		return "", fmt.Errorf("failed to resolve short link %s: %w", link, err)
+	}
+	resp.Body.Close()
+
+	if target == "" {
+		return "", fmt.Errorf("%w: short link %s does not redirect to open.spotify.com", ErrInvalidLink, link)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,157 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	testAlbumID    = "4aawyAB9vmqN3uQ7FjRGTy"
+	testPlaylistID = "37i9dQZF1DXcBWIGoYBM5M"
+	testTrackID    = "11dFghVXANMlKmJXsNCbNl"
+)
+
+func TestParseResource(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Resource
+		err   error
+	}{
+		{"bare ID", testAlbumID, Resource{AlbumResource, testAlbumID}, nil},
+		{"bare ID with spaces", "  " + testAlbumID + "\n", Resource{AlbumResource, testAlbumID}, nil},
+		{"URI", "spotify:track:" + testTrackID, Resource{TrackResource, testTrackID}, nil},
+		{"legacy user URI", "spotify:user:someone:playlist:" + testPlaylistID, Resource{PlaylistResource, testPlaylistID}, nil},
+		{"link", "https://open.spotify.com/album/" + testAlbumID, Resource{AlbumResource, testAlbumID}, nil},
+		{"link without scheme", "open.spotify.com/track/" + testTrackID, Resource{TrackResource, testTrackID}, nil},
+		{"link with share query", "https://open.spotify.com/playlist/" + testPlaylistID + "?si=abc123&pt=x", Resource{PlaylistResource, testPlaylistID}, nil},
+		{"link with trailing slash", "https://open.spotify.com/album/" + testAlbumID + "/", Resource{AlbumResource, testAlbumID}, nil},
+		{"locale", "https://open.spotify.com/intl-de/album/" + testAlbumID, Resource{AlbumResource, testAlbumID}, nil},
+		{"locale with region", "https://open.spotify.com/intl-pt-BR/track/" + testTrackID + "?si=1", Resource{TrackResource, testTrackID}, nil},
+		{"embed", "https://open.spotify.com/embed/playlist/" + testPlaylistID, Resource{PlaylistResource, testPlaylistID}, nil},
+		{"legacy user playlist", "https://open.spotify.com/user/someone/playlist/" + testPlaylistID, Resource{PlaylistResource, testPlaylistID}, nil},
+		{"www host", "https://www.open.spotify.com/artist/" + testAlbumID, Resource{ArtistResource, testAlbumID}, nil},
+		{"play host", "https://play.spotify.com/album/" + testAlbumID, Resource{AlbumResource, testAlbumID}, nil},
+		{"empty", "  ", Resource{}, ErrInvalidLink},
+		{"short ID", "4aawyAB9vmqN3uQ7FjRGT", Resource{}, ErrInvalidLink},
+		{"bad ID in link", "https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGT-", Resource{}, ErrInvalidLink},
+		{"bad ID in URI", "spotify:album:" + testAlbumID + "x", Resource{}, ErrInvalidLink},
+		{"injected path", "https://open.spotify.com/album/" + testAlbumID[:21] + "%2F", Resource{}, ErrInvalidLink},
+		{"unsupported type", "https://open.spotify.com/show/" + testAlbumID, Resource{}, ErrInvalidLink},
+		{"other host", "https://example.com/album/" + testAlbumID, Resource{}, ErrInvalidLink},
+		{"lookalike host", "https://open.spotify.com.example.com/album/" + testAlbumID, Resource{}, ErrInvalidLink},
+		{"short link", "https://spotify.link/abc123", Resource{}, ErrShortLink},
+		{"short link without scheme", "spotify.app.link/abc123", Resource{}, ErrShortLink},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseResource(test.input, AlbumResource)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ParseResource(%q) error = %v, want %v", test.input, err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("ParseResource(%q) = %+v, want %+v", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+// redirectTransport answers every request with a redirect to the Location registered for its
+// URL, or 404 when there is none.
+type redirectTransport map[string]string
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	if location, ok := t[req.URL.String()]; ok {
+		resp.StatusCode = http.StatusFound
+		resp.Header.Set("Location", location)
+	}
+	return resp, nil
+}
+
+func TestResolveResource(t *testing.T) {
+	albumLink := "https://open.spotify.com/album/" + testAlbumID + "?si=abc"
+
+	tests := []struct {
+		name      string
+		input     string
+		redirects redirectTransport
+		want      Resource
+		err       error
+	}{
+		{
+			name:      "direct",
+			input:     "https://spotify.link/abc",
+			redirects: redirectTransport{"https://spotify.link/abc": albumLink},
+			want:      Resource{AlbumResource, testAlbumID},
+		},
+		{
+			name:  "through other short link hosts",
+			input: "spotify.link/abc",
+			redirects: redirectTransport{
+				"https://spotify.link/abc":               "https://spotify.app.link/abc",
+				"https://spotify.app.link/abc":           "https://spotify-alternate.app.link/abc",
+				"https://spotify-alternate.app.link/abc": "https://open.spotify.com/intl-fr/playlist/" + testPlaylistID,
+			},
+			want: Resource{PlaylistResource, testPlaylistID},
+		},
+		{
+			name:      "redirect off Spotify",
+			input:     "https://spotify.link/abc",
+			redirects: redirectTransport{"https://spotify.link/abc": "http://169.254.169.254/latest/meta-data"},
+			err:       ErrInvalidLink,
+		},
+		{
+			name:      "redirect to lookalike host",
+			input:     "https://spotify.link/abc",
+			redirects: redirectTransport{"https://spotify.link/abc": "https://open.spotify.com.example.com/album/" + testAlbumID},
+			err:       ErrInvalidLink,
+		},
+		{
+			name:  "redirect loop",
+			input: "https://spotify.link/a",
+			redirects: redirectTransport{
+				"https://spotify.link/a": "https://spotify.link/b",
+				"https://spotify.link/b": "https://spotify.link/a",
+			},
+			err: ErrInvalidLink,
+		},
+		{
+			name:      "no redirect",
+			input:     "https://spotify.link/gone",
+			redirects: redirectTransport{},
+			err:       ErrInvalidLink,
+		},
+		{
+			name:      "redirect to a bad ID",
+			input:     "https://spotify.link/abc",
+			redirects: redirectTransport{"https://spotify.link/abc": "https://open.spotify.com/album/nope"},
+			err:       ErrInvalidLink,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shortLinkTransport = test.redirects
+			t.Cleanup(func() { shortLinkTransport = nil })
+
+			got, err := ResolveResource(context.Background(), test.input, AlbumResource)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ResolveResource(%q) error = %v, want %v", test.input, err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("ResolveResource(%q) = %+v, want %+v", test.input, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMaxItems caps how many items a list method reads when AuthClient.MaxItems is 0.
+	DefaultMaxItems = 2000
+	// pageSize is the most items every Spotify list endpoint returns per page.
+	pageSize = 50
+)
+
+// Page is a Spotify paging object: one page of a list, with a link to the next one.
+type Page[T any] struct {
+	Href     string `json:"href"`
+	Limit    int    `json:"limit"`
+	Next     string `json:"next"`
+	Offset   int    `json:"offset"`
+	Previous string `json:"previous"`
+	Total    int    `json:"total"`
+	Items    []T    `json:"items"`
+}
+
+// maxItems returns how many items list methods read at most.
+func (c *AuthClient) maxItems() int {
+	if c.MaxItems > 0 {
+		return c.MaxItems
+	}
+	return DefaultMaxItems
+}
+
+// Pages fetches the list at endpoint page by page, following each page's next link until the
+// list ends or maxItems items have been fetched. The page that reaches maxItems is trimmed to it,
+// so no more than maxItems items are yielded; a maxItems of 0 or less means no cap. Fetching
+// stops after the first error, which is yielded with a nil page.
+func Pages[T any](ctx context.Context, c *AuthClient, endpoint string, maxItems int) iter.Seq2[*Page[T], error] {
+	return func(yield func(*Page[T], error) bool) {
+		next, err := withPageSize(endpoint)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		fetched := 0
+		for next != "" {
+			page, err := getPage[T](ctx, c, next)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if maxItems > 0 && fetched+len(page.Items) > maxItems {
+				page.Items = page.Items[:maxItems-fetched]
+			}
+
+			if !yield(page, nil) {
+				return
+			}
+
+			fetched += len(page.Items)
+			if len(page.Items) == 0 || (maxItems > 0 && fetched >= maxItems) {
+				return
+			}
+
+			next, err = relativeEndpoint(page.Next)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// Items yields the items of the list at endpoint, up to maxItems of them, fetching pages as
+// needed. See Pages.
+func Items[T any](ctx context.Context, c *AuthClient, endpoint string, maxItems int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range Pages[T](ctx, c, endpoint, maxItems) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect reads every item of the list at endpoint, up to the client's maximum.
+func collect[T any](ctx context.Context, c *AuthClient, endpoint string) ([]T, error) {
+	var items []T
+	for item, err := range Items[T](ctx, c, endpoint, c.maxItems()) {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func getPage[T any](ctx context.Context, c *AuthClient, endpoint string) (*Page[T], error) {
+	resp, err := c.Get(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var page Page[T]
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	return &page, nil
+}
+
+// withPageSize asks for the largest pages, unless endpoint already sets a limit.
+func withPageSize(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %s: %w", endpoint, err)
+	}
+
+	query := u.Query()
+	if query.Get("limit") == "" {
+		query.Set("limit", strconv.Itoa(pageSize))
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String(), nil
+}
+
+// relativeEndpoint turns a page's absolute next link into an endpoint for AuthClient.Get.
+// An empty link, the end of the list, stays empty.
+func relativeEndpoint(next string) (string, error) {
+	if next == "" {
+		return "", nil
+	}
+
+	endpoint, ok := strings.CutPrefix(next, BaseURL)
+	if !ok {
+		return "", fmt.Errorf("next page link %s is not a Spotify API URL", next)
+	}
+
+	return endpoint, nil
+}
@@ -0,0 +1,106 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// fakeList serves a list of total numbered items at any endpoint, in pages linked by next.
+type fakeList struct {
+	total int
+	pages int
+}
+
+func (l *fakeList) RoundTrip(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		return nil, fmt.Errorf("request to %s has no limit", req.URL)
+	}
+	l.pages++
+
+	page := Page[int]{Offset: offset, Limit: limit, Total: l.total}
+	for i := offset; i < min(offset+limit, l.total); i++ {
+		page.Items = append(page.Items, i)
+	}
+	if offset+limit < l.total {
+		next := *req.URL
+		query.Set("offset", strconv.Itoa(offset+limit))
+		next.RawQuery = query.Encode()
+		page.Next = next.String()
+	}
+
+	return jsonResponse(req, page)
+}
+
+func TestItemsCap(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		maxItems int
+		want     int
+		pages    int
+	}{
+		{"shorter than the cap", 70, 100, 70, 2},
+		{"cap inside a page", 300, 120, 120, 3},
+		{"cap at a page boundary", 300, 100, 100, 2},
+		{"no cap", 130, 0, 130, 3},
+		{"empty list", 0, 100, 0, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list := &fakeList{total: test.total}
+			client := &AuthClient{Client: &http.Client{Transport: list}, AccessToken: "token"}
+
+			var pageItems, items int
+			for page, err := range Pages[int](context.Background(), client, "/me/top/tracks", test.maxItems) {
+				if err != nil {
+					t.Fatalf("Pages error = %v", err)
+				}
+				for _, item := range page.Items {
+					if item != pageItems {
+						t.Fatalf("item %d is %d", pageItems, item)
+					}
+					pageItems++
+				}
+			}
+
+			for item, err := range Items[int](context.Background(), client, "/me/top/tracks", test.maxItems) {
+				if err != nil {
+					t.Fatalf("Items error = %v", err)
+				}
+				if item != items {
+					t.Fatalf("item %d is %d", items, item)
+				}
+				items++
+			}
+
+			if pageItems != test.want || items != test.want {
+				t.Errorf("Pages yielded %d items and Items %d, want %d", pageItems, items, test.want)
+			}
+			if list.pages != 2*test.pages {
+				t.Errorf("fetched %d pages, want %d", list.pages/2, test.pages)
+			}
+		})
+	}
+}
+
+func TestGetTopItemsCap(t *testing.T) {
+	client := &AuthClient{Client: &http.Client{Transport: &fakeList{total: 300}}, AccessToken: "token", MaxItems: 120}
+
+	top, err := client.GetTopItems(context.Background(), Tracks)
+	if err != nil {
+		t.Fatalf("GetTopItems error = %v", err)
+	}
+	if len(top.Items) != 120 {
+		t.Errorf("GetTopItems returned %d items, want 120", len(top.Items))
+	}
+	if top.Total != 300 || top.Next == "" {
+		t.Errorf("GetTopItems total = %d, next = %q, want the full total and a next link", top.Total, top.Next)
+	}
+}
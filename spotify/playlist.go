@@ -1,14 +1,34 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"slices"
 )
 
-func (c *AuthClient) CreatePlaylist(userID string, playlist NewPlaylist) (*NewPlaylistResponse, error) {
+// MaxPlaylistBatch is the most tracks Spotify adds or removes in one request.
+const MaxPlaylistBatch = 100
+
+// PlaylistProgress records how far writing tracks to a playlist got, so a failed write can be
+// resumed. See AuthClient.AddPlaylistItems.
+type PlaylistProgress struct {
+	// Offset is the playlist position the first track is written at.
+	Offset int
+	// Written counts the tracks written so far.
+	Written int
+	// Pending is set while a batch is being written, and stays set when its outcome is unknown.
+	Pending bool
+	// SnapshotID is the playlist version after the last batch written.
+	SnapshotID string
+}
+
+func (c *AuthClient) CreatePlaylist(ctx context.Context, userID string, playlist NewPlaylist) (*NewPlaylistResponse, error) {
 	// Use the Post method with the playlist payload
-	resp, err := c.Post(fmt.Sprintf("/users/%s/playlists", userID), playlist)
+	resp, err := c.Post(ctx, fmt.Sprintf("/users/%s/playlists", userID), playlist)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get response: %w", err)
 	}
@@ -30,7 +50,13 @@ func (c *AuthClient) CreatePlaylist(userID string, playlist NewPlaylist) (*NewPl
 	return &newPlaylistResponse, nil
 }
 
-func (c *AuthClient) AddToPlaylist(playlistID string, uris []string, position *int) error {
+// AddToPlaylist inserts up to 100 URIs into the playlist at position, or appends them when
+// position is nil, and returns the playlist's new snapshot ID. Use AddPlaylistItems for more.
+func (c *AuthClient) AddToPlaylist(ctx context.Context, playlistID string, uris []string, position *int) (string, error) {
+	if len(uris) > MaxPlaylistBatch {
+		return "", fmt.Errorf("cannot add %d tracks in one request, the limit is %d", len(uris), MaxPlaylistBatch)
+	}
+
 	payload := map[string]interface{}{
 		"uris": uris,
 	}
@@ -40,93 +66,206 @@ func (c *AuthClient) AddToPlaylist(playlistID string, uris []string, position *i
 	}
 
 	endpoint := fmt.Sprintf("/playlists/%s/tracks", playlistID)
-	resp, err := c.Post(endpoint, payload)
+	resp, err := c.Post(ctx, endpoint, payload)
 	if err != nil {
-		return fmt.Errorf("failed to get response: %w", err)
+		return "", fmt.Errorf("failed to get response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Handle non-2xx responses
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request to add tracks failed with status %d: %s", resp.StatusCode, string(body))
+	return readSnapshotID(resp)
+}
+
+// AddPlaylistItems writes the URIs to the playlist in order, in batches of up to
+// MaxPlaylistBatch, starting at progress.Offset. progress is updated after every batch. If the
+// write fails, calling AddPlaylistItems again with the same URIs and progress resumes after the
+// last batch that was written. A batch whose response was lost is checked against the playlist
+// first, so no track is added twice.
+func (c *AuthClient) AddPlaylistItems(ctx context.Context, playlistID string, uris []string, progress *PlaylistProgress) error {
+	for progress.Written < len(uris) {
+		batch := uris[progress.Written:min(progress.Written+MaxPlaylistBatch, len(uris))]
+		position := progress.Offset + progress.Written
+
+		if progress.Pending {
+			written, err := c.batchWritten(ctx, playlistID, position, batch)
+			if err != nil {
+				return fmt.Errorf("failed to check tracks at position %d: %w", position, err)
+			}
+			if written {
+				progress.Written += len(batch)
+				progress.Pending = false
+				continue
+			}
+		}
+
+		progress.Pending = true
+		snapshot, err := c.AddToPlaylist(ctx, playlistID, batch, &position)
+		if err != nil {
+			return fmt.Errorf("failed to add tracks %d to %d: %w", progress.Written+1, progress.Written+len(batch), err)
+		}
+
+		progress.Written += len(batch)
+		progress.Pending = false
+		progress.SnapshotID = snapshot
 	}
 
 	return nil
 }
 
-func (c *AuthClient) GetUserPlaylists(userID string) ([]Playlist, error) {
-	// Construct the URL for the request
-	endpoint := fmt.Sprintf("/users/%s/playlists", userID)
+// batchWritten reports whether the playlist already holds the batch at position.
+func (c *AuthClient) batchWritten(ctx context.Context, playlistID string, position int, batch []string) (bool, error) {
+	endpoint := fmt.Sprintf("/playlists/%s/tracks?offset=%d&limit=%d&fields=items(track(uri))", playlistID, position, len(batch))
 
-	resp, err := c.Get(endpoint)
+	page, err := getPage[PlaylistItem](ctx, c, endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get response: %w", err)
+		return false, err
 	}
-	defer resp.Body.Close()
 
-	// Handle non-2xx responses
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get playlists: status %d, response %s", resp.StatusCode, string(body))
+	if len(page.Items) != len(batch) {
+		return false, nil
+	}
+	for i, item := range page.Items {
+		if item.Track == nil || item.Track.URI != batch[i] {
+			return false, nil
+		}
 	}
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+	return true, nil
+}
+
+// ReplacePlaylistItems replaces every track of the playlist with the URIs and returns the
+// playlist's new snapshot ID. The first batch replaces the tracks, the rest are added after it.
+func (c *AuthClient) ReplacePlaylistItems(ctx context.Context, playlistID string, uris []string) (string, error) {
+	first := uris[:min(MaxPlaylistBatch, len(uris))]
+
+	resp, err := c.Put(ctx, fmt.Sprintf("/playlists/%s/tracks", playlistID), map[string]any{
+		"uris": first,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return "", fmt.Errorf("failed to replace tracks: %w", err)
 	}
 
-	// Parse the JSON response
-	var playlistsResponse PlaylistsResponse
-	if err := json.Unmarshal(body, &playlistsResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	snapshot, err := readSnapshotID(resp)
+	if err != nil {
+		return "", err
+	}
+
+	progress := &PlaylistProgress{Written: len(first), SnapshotID: snapshot}
+	if err := c.AddPlaylistItems(ctx, playlistID, uris, progress); err != nil {
+		return "", err
 	}
 
-	// Return the list of playlists
-	return playlistsResponse.Items, nil
+	return progress.SnapshotID, nil
 }
 
-func (c *AuthClient) GetPlaylistTracks(playlistID string) ([]Track, error) {
-	endpoint := fmt.Sprintf("/playlists/%s/tracks", playlistID)
+// ReorderPlaylistItems moves rangeLength tracks starting at rangeStart to before the track at
+// insertBefore, and returns the playlist's new snapshot ID. When snapshotID is set, positions
+// refer to that version of the playlist.
+func (c *AuthClient) ReorderPlaylistItems(ctx context.Context, playlistID string, rangeStart, insertBefore, rangeLength int, snapshotID string) (string, error) {
+	payload := map[string]any{
+		"range_start":   rangeStart,
+		"insert_before": insertBefore,
+		"range_length":  rangeLength,
+	}
+	if snapshotID != "" {
+		payload["snapshot_id"] = snapshotID
+	}
 
-	resp, err := c.Get(endpoint)
+	resp, err := c.Put(ctx, fmt.Sprintf("/playlists/%s/tracks", playlistID), payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get response: %w", err)
+		return "", fmt.Errorf("failed to reorder tracks: %w", err)
 	}
+
+	return readSnapshotID(resp)
+}
+
+// RemovePlaylistItems removes every occurrence of the URIs from the playlist, in batches of up
+// to MaxPlaylistBatch, and returns the playlist's new snapshot ID. When snapshotID is set, the
+// first batch is removed from that version of the playlist.
+func (c *AuthClient) RemovePlaylistItems(ctx context.Context, playlistID string, uris []string, snapshotID string) (string, error) {
+	for batch := range slices.Chunk(uris, MaxPlaylistBatch) {
+		tracks := make([]map[string]string, len(batch))
+		for i, uri := range batch {
+			tracks[i] = map[string]string{"uri": uri}
+		}
+
+		payload := map[string]any{"tracks": tracks}
+		if snapshotID != "" {
+			payload["snapshot_id"] = snapshotID
+		}
+
+		resp, err := c.Delete(ctx, fmt.Sprintf("/playlists/%s/tracks", playlistID), payload)
+		if err != nil {
+			return "", fmt.Errorf("failed to remove tracks: %w", err)
+		}
+
+		snapshotID, err = readSnapshotID(resp)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return snapshotID, nil
+}
+
+// readSnapshotID reads the snapshot ID from a playlist change response and closes its body.
+func readSnapshotID(resp *http.Response) (string, error) {
 	defer resp.Body.Close()
 
-	// Handle non-2xx responses
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get playlist tracks: status %d, response %s", resp.StatusCode, string(body))
+	var snapshot SnapshotResponse
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	// Read the response body
+	return snapshot.SnapshotID, nil
+}
+
+// GetPlaylist returns the playlist's details, without its tracks. See GetPlaylistTracks.
+func (c *AuthClient) GetPlaylist(ctx context.Context, playlistID string) (*Playlist, error) {
+	query := url.Values{}
+	query.Set("fields", "id,name,description,uri,snapshot_id")
+
+	resp, err := c.Get(ctx, fmt.Sprintf("/playlists/%s?%s", playlistID, query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist: %w", err)
+	}
+	defer resp.Body.Close()
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Parse the JSON response
-	var playlistTracksResponse PlaylistTracksResponse
-	if err := json.Unmarshal(body, &playlistTracksResponse); err != nil {
+	var playlist Playlist
+	if err := json.Unmarshal(body, &playlist); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	// Extract track details
+	return &playlist, nil
+}
+
+// GetUserPlaylists returns every playlist of the user, reading as many pages as it takes.
+func (c *AuthClient) GetUserPlaylists(ctx context.Context, userID string) ([]Playlist, error) {
+	playlists, err := collect[Playlist](ctx, c, fmt.Sprintf("/users/%s/playlists", userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlists: %w", err)
+	}
+
+	return playlists, nil
+}
+
+// GetPlaylistTracks returns every track of the playlist, reading as many pages as it takes.
+// Episodes, local files and tracks no longer on Spotify are left out.
+func (c *AuthClient) GetPlaylistTracks(ctx context.Context, playlistID string) ([]Track, error) {
+	items, err := collect[PlaylistItem](ctx, c, fmt.Sprintf("/playlists/%s/tracks", playlistID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist tracks: %w", err)
+	}
+
 	var tracks []Track
-	for _, item := range playlistTracksResponse.Items {
-		track := item.Track
-		artistNames := make([]string, len(track.Artists))
-		for i, artist := range track.Artists {
-			artistNames[i] = artist.Name
+	for _, item := range items {
+		if item.Track == nil || item.Track.Type != "track" || item.Track.IsLocal {
+			continue
 		}
-		tracks = append(tracks, Track{
-			ID:   track.ID,
-			Name: track.Name,
-			URI:  track.URI,
-		})
+		tracks = append(tracks, *item.Track)
 	}
 
 	return tracks, nil
@@ -0,0 +1,144 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	resumePlaylistID   = "3cEYpjA9oz9GiPac4AsH4n"
+	resumePlaylistPath = "/v1/playlists/" + resumePlaylistID + "/tracks"
+)
+
+// fakePlaylist serves a playlist's tracks endpoint from memory. The add listed in lose has its
+// response lost: the tracks are added, or not when apply is false, and the request fails.
+type fakePlaylist struct {
+	uris  []string
+	adds  int
+	lose  int
+	apply bool
+}
+
+var errConnectionReset = errors.New("connection reset")
+
+func (p *fakePlaylist) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "api.spotify.com" || req.URL.Path != resumePlaylistPath {
+		return nil, fmt.Errorf("unexpected request to %s", req.URL)
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		query := req.URL.Query()
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		page := Page[PlaylistItem]{Offset: offset, Limit: limit, Total: len(p.uris)}
+		for _, uri := range p.uris[min(offset, len(p.uris)):min(offset+limit, len(p.uris))] {
+			page.Items = append(page.Items, PlaylistItem{Track: &Track{URI: uri}})
+		}
+		return jsonResponse(req, page)
+
+	case http.MethodPost:
+		var body struct {
+			URIs     []string `json:"uris"`
+			Position int      `json:"position"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+
+		p.adds++
+		lost := p.adds == p.lose
+		if !lost || p.apply {
+			p.uris = slices.Insert(p.uris, body.Position, body.URIs...)
+		}
+		if lost {
+			return nil, errConnectionReset
+		}
+		return jsonResponse(req, SnapshotResponse{SnapshotID: "snapshot-" + strconv.Itoa(p.adds)})
+	}
+
+	return nil, fmt.Errorf("unexpected %s request", req.Method)
+}
+
+func jsonResponse(req *http.Request, v any) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(body))),
+		Request:    req,
+	}, nil
+}
+
+func testURIs(n int) []string {
+	uris := make([]string, n)
+	for i := range uris {
+		uris[i] = fmt.Sprintf("spotify:track:%022d", i)
+	}
+	return uris
+}
+
+func TestAddPlaylistItemsResume(t *testing.T) {
+	existing := []string{"spotify:track:existing1", "spotify:track:existing2"}
+	uris := testURIs(2*MaxPlaylistBatch + 10)
+
+	tests := []struct {
+		name string
+		// lose is the add whose response is lost, counting from 1.
+		lose int
+		// apply is whether the lost add reached the playlist anyway.
+		apply bool
+		// written is how many tracks the first call reports written.
+		written int
+	}{
+		{"first batch applied", 1, true, 0},
+		{"first batch not applied", 1, false, 0},
+		{"middle batch applied", 2, true, MaxPlaylistBatch},
+		{"middle batch not applied", 2, false, MaxPlaylistBatch},
+		{"last batch applied", 3, true, 2 * MaxPlaylistBatch},
+		{"last batch not applied", 3, false, 2 * MaxPlaylistBatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			playlist := &fakePlaylist{uris: slices.Clone(existing), lose: test.lose, apply: test.apply}
+			client := &AuthClient{Client: &http.Client{Transport: playlist}, AccessToken: "token"}
+			progress := &PlaylistProgress{Offset: len(existing)}
+
+			err := client.AddPlaylistItems(context.Background(), resumePlaylistID, uris, progress)
+			if !errors.Is(err, errConnectionReset) {
+				t.Fatalf("first AddPlaylistItems error = %v, want %v", err, errConnectionReset)
+			}
+			if progress.Written != test.written || !progress.Pending {
+				t.Fatalf("progress after lost response = %+v, want %d written and pending", progress, test.written)
+			}
+
+			if err := client.AddPlaylistItems(context.Background(), resumePlaylistID, uris, progress); err != nil {
+				t.Fatalf("resumed AddPlaylistItems error = %v", err)
+			}
+
+			want := append(slices.Clone(existing), uris...)
+			if !slices.Equal(playlist.uris, want) {
+				t.Errorf("playlist has %d tracks, want %d in order without repeats", len(playlist.uris), len(want))
+			}
+			if progress.Written != len(uris) || progress.Pending {
+				t.Errorf("final progress = %+v, want %d written and not pending", progress, len(uris))
+			}
+			if progress.SnapshotID == "" {
+				t.Error("final progress has no snapshot ID")
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package spotify
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/ratelimit"
+)
+
+const (
+	// DefaultRequestsPerSecond stays under the rolling 30 second limit Spotify applies to apps in
+	// development mode.
+	DefaultRequestsPerSecond = 10
+	// maxRetries is how many times a throttled or failed request is retried.
+	maxRetries = 4
+	// baseBackoff is the wait before the first retry of a server error. It doubles with each retry.
+	baseBackoff = 500 * time.Millisecond
+	// maxBackoff caps the wait between retries of a server error.
+	maxBackoff = 30 * time.Second
+	// maxRetryAfter is the longest Retry-After that is waited out. Longer bans fail the request.
+	maxRetryAfter = 2 * time.Minute
+)
+
+// limiter paces requests from every AuthClient, since Spotify rate limits the app as a whole.
+var limiter = ratelimit.NewLimiter(DefaultRequestsPerSecond)
+
+// metrics counts requests to the Spotify API, how often they were throttled or retried, and the
+// time spent waiting on the rate limiter and between retries. They are served at /debug/vars
+// when running locally, and logged by LogMetrics.
+var metrics = expvar.NewMap("spotify")
+
+const (
+	metricRequests     = "requests"
+	metricThrottled    = "throttled"
+	metricServerErrors = "serverErrors"
+	metricRetries      = "retries"
+	metricWaitMillis   = "waitMilliseconds"
+)
+
+// SetRateLimit changes how many requests per second all clients may send to the Spotify API.
+func SetRateLimit(requestsPerSecond float64) {
+	limiter.SetRate(requestsPerSecond)
+}
+
+// LogMetrics logs how the counters in metrics changed over each interval until ctx ends, so
+// throttling shows up in production logs too. Intervals without requests aren't logged.
+func LogMetrics(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := metricValues()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := metricValues()
+			if current[metricRequests] == last[metricRequests] {
+				continue
+			}
+
+			delta := func(key string) int64 { return current[key] - last[key] }
+			logger.LogInfo(ctx, "Spotify API in the last %s: %d requests, %d throttled, %d server errors, %d retries, %dms waiting",
+				interval, delta(metricRequests), delta(metricThrottled), delta(metricServerErrors), delta(metricRetries), delta(metricWaitMillis))
+			last = current
+		}
+	}
+}
+
+// metricValues returns the current value of every counter in metrics.
+func metricValues() map[string]int64 {
+	values := make(map[string]int64)
+	metrics.Do(func(kv expvar.KeyValue) {
+		if counter, ok := kv.Value.(*expvar.Int); ok {
+			values[kv.Key] = counter.Value()
+		}
+	})
+	return values
+}
@@ -0,0 +1,231 @@
+package spotify
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ericflores108/spotify/match"
+)
+
+// candidateLimit is how many search results are scored when resolving a track.
+const candidateLimit = 10
+
+const (
+	// compilationPenalty ranks tracks on compilations below the same track on its own album.
+	compilationPenalty = 0.1
+	// imitationPenalty ranks karaoke versions, tributes and covers far enough down that they
+	// only match when nothing else does, and usually not at all.
+	imitationPenalty = 0.5
+)
+
+// imitationWords mark karaoke versions, tributes and covers in a track, album or artist name.
+var imitationWords = regexp.MustCompile(`(?i)\b(karaoke|tribute|covers?|made famous|in the style of|originally performed|made popular|backing track|lullaby)\b`)
+
+type marketKey struct{}
+
+// WithMarket returns a context under which tracks are only resolved to releases playable in the
+// market, an ISO 3166-1 alpha-2 country code such as MeResponse.Country.
+func WithMarket(ctx context.Context, market string) context.Context {
+	if market == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, marketKey{}, market)
+}
+
+// MarketFromContext returns the market set with WithMarket, or "" for any market.
+func MarketFromContext(ctx context.Context) string {
+	market, _ := ctx.Value(marketKey{}).(string)
+	return market
+}
+
+// TrackQuery describes a track to find on Spotify. ISRC, when a source knows it, identifies the
+// recording itself and is looked up before the name and artist.
+type TrackQuery struct {
+	Name   string
+	Artist string
+	ISRC   string
+}
+
+type candidate struct {
+	track *Track
+	// score is how well the track's name and artists match the query.
+	score float64
+	// rank is the score less penalties for compilations and imitations.
+	rank float64
+}
+
+// ResolveTrack returns the Spotify track that best matches the query, or nil when no search
+// result matches well enough. Results are scored by normalized title and artist, see
+// match.Score, and originals are preferred over compilations, karaoke versions, tributes and
+// covers. A track found on a compilation is swapped for the same recording, by ISRC, on its
+// original release when Spotify has it. Under a context from WithMarket, only tracks playable
+// in that market are considered.
+func (c *AuthClient) ResolveTrack(ctx context.Context, query TrackQuery) (*Track, error) {
+	if query.ISRC != "" {
+		track, err := c.trackByISRC(ctx, query.ISRC, query)
+		if err != nil || track != nil {
+			return track, err
+		}
+	}
+
+	var queries []string
+	if query.Artist != "" && !strings.Contains(query.Name, " by ") {
+		queries = append(queries, fmt.Sprintf("track:%s artist:%s", match.CleanTitle(query.Name), query.Artist))
+		// field filters miss titles and names written slightly differently
+		queries = append(queries, fmt.Sprintf("%s %s", match.CleanTitle(query.Name), query.Artist))
+	} else {
+		queries = append(queries, fmt.Sprintf("track:%s", match.CleanTitle(query.Name)))
+	}
+
+	for _, q := range queries {
+		tracks, err := c.searchTracks(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		best := bestCandidate(tracks, query)
+		if best == nil {
+			continue
+		}
+
+		if best.Album.AlbumType == "compilation" && best.ExternalIDs.ISRC != "" {
+			original, err := c.trackByISRC(ctx, best.ExternalIDs.ISRC, query)
+			if err != nil {
+				return nil, err
+			}
+			if original != nil {
+				return original, nil
+			}
+		}
+
+		return best, nil
+	}
+
+	return nil, nil
+}
+
+// trackByISRC returns the best release of the recording with the ISRC, or nil when Spotify has
+// none. Every release shares the recording, so the query's name and artist only rank them.
+func (c *AuthClient) trackByISRC(ctx context.Context, isrc string, query TrackQuery) (*Track, error) {
+	tracks, err := c.searchTracks(ctx, "isrc:"+isrc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tracks) == 0 {
+		return nil, nil
+	}
+
+	candidates := rankCandidates(tracks, query)
+	return candidates[0].track, nil
+}
+
+// bestCandidate returns the best ranked track that matches the query well enough, or nil.
+func bestCandidate(tracks []Track, query TrackQuery) *Track {
+	for _, candidate := range rankCandidates(tracks, query) {
+		if candidate.score >= match.Threshold && candidate.rank >= match.Threshold-compilationPenalty {
+			return candidate.track
+		}
+	}
+	return nil
+}
+
+// rankCandidates scores the tracks against the query, best first. Of equally ranked tracks,
+// the earliest release wins, then the most popular.
+func rankCandidates(tracks []Track, query TrackQuery) []candidate {
+	// an imitation is only penalized when the query isn't asking for one
+	wantsImitation := imitationWords.MatchString(query.Name + " " + query.Artist)
+
+	candidates := make([]candidate, 0, len(tracks))
+	for i := range tracks {
+		track := &tracks[i]
+
+		artists := make([]string, len(track.Artists))
+		for j, artist := range track.Artists {
+			artists[j] = artist.Name
+		}
+
+		score := match.Score(track.Name, artists, query.Name, query.Artist)
+		rank := score
+		if track.Album.AlbumType == "compilation" {
+			rank -= compilationPenalty
+		}
+		if !wantsImitation && imitationWords.MatchString(track.Name+" "+track.Album.Name+" "+strings.Join(artists, " ")) {
+			rank -= imitationPenalty
+		}
+
+		candidates = append(candidates, candidate{track: track, score: score, rank: rank})
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if a.rank != b.rank {
+			return cmp.Compare(b.rank, a.rank)
+		}
+		if a.track.Album.ReleaseDate != b.track.Album.ReleaseDate {
+			return releaseOrder(a.track.Album.ReleaseDate, b.track.Album.ReleaseDate)
+		}
+		return cmp.Compare(b.track.Popularity, a.track.Popularity)
+	})
+
+	return candidates
+}
+
+// releaseOrder orders release dates, which may be a year, year-month or full date, earliest
+// first. Unknown dates sort last.
+func releaseOrder(a, b string) int {
+	switch {
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// searchTracks runs a track search and returns up to candidateLimit results, leaving out
+// tracks that aren't playable in the context's market.
+func (c *AuthClient) searchTracks(ctx context.Context, q string) ([]Track, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	query.Set("type", "track")
+	query.Set("limit", strconv.Itoa(candidateLimit))
+
+	market := MarketFromContext(ctx)
+	if market != "" {
+		query.Set("market", market)
+	}
+
+	resp, err := c.Get(ctx, fmt.Sprintf("/search?%s", query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var searchResponse SearchResponse
+	if err := json.Unmarshal(body, &searchResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	tracks := searchResponse.Tracks.Items
+	if market != "" {
+		// Spotify only reports playability when a market is given
+		tracks = slices.DeleteFunc(tracks, func(track Track) bool {
+			return !track.IsPlayable
+		})
+	}
+
+	return tracks, nil
+}
@@ -0,0 +1,53 @@
+package spotify
+
+import "testing"
+
+func testTrack(uri, name, artist, albumType, albumName, released string, popularity int) Track {
+	return Track{
+		URI:        uri,
+		Name:       name,
+		Artists:    []Artist{{Name: artist}},
+		Album:      Album{AlbumType: albumType, Name: albumName, ReleaseDate: released},
+		Popularity: popularity,
+	}
+}
+
+func TestBestCandidate(t *testing.T) {
+	original := testTrack("original", "Heroes - 2017 Remaster", "David Bowie", "album", "Heroes", "1977-10-14", 60)
+	compilation := testTrack("compilation", "Heroes", "David Bowie", "compilation", "Best of Bowie", "2002-10-21", 80)
+	karaoke := testTrack("karaoke", "Heroes", "David Bowie", "album", "Karaoke Hits of the 70s", "2010", 5)
+	cover := testTrack("cover", "Heroes", "Peter Gabriel", "album", "Scratch My Back", "2010-02-15", 40)
+	reissue := testTrack("reissue", "Heroes", "David Bowie", "album", "Heroes", "1999", 30)
+	featured := testTrack("featured", "Say So", "Doja Cat", "album", "Hot Pink", "2019-11-07", 90)
+	featured.Artists = append(featured.Artists, Artist{Name: "Nicki Minaj"})
+
+	tests := []struct {
+		name   string
+		tracks []Track
+		query  TrackQuery
+		want   string
+	}{
+		{"original over compilation", []Track{compilation, original}, TrackQuery{Name: "Heroes", Artist: "David Bowie"}, "original"},
+		{"compilation when it's all there is", []Track{compilation}, TrackQuery{Name: "Heroes", Artist: "David Bowie"}, "compilation"},
+		{"not karaoke", []Track{karaoke, compilation}, TrackQuery{Name: "Heroes", Artist: "David Bowie"}, "compilation"},
+		{"karaoke alone", []Track{karaoke}, TrackQuery{Name: "Heroes", Artist: "David Bowie"}, ""},
+		{"karaoke when asked for", []Track{karaoke}, TrackQuery{Name: "Heroes (Karaoke)", Artist: "David Bowie"}, "karaoke"},
+		{"not another artist's cover", []Track{cover}, TrackQuery{Name: "Heroes", Artist: "David Bowie"}, ""},
+		{"earliest release of equals", []Track{reissue, original}, TrackQuery{Name: "Heroes", Artist: "David Bowie"}, "original"},
+		{"featured artist", []Track{featured}, TrackQuery{Name: "Say So", Artist: "Nicki Minaj"}, "featured"},
+		{"no match", []Track{original}, TrackQuery{Name: "Hello", Artist: "Adele"}, ""},
+		{"no tracks", nil, TrackQuery{Name: "Heroes", Artist: "David Bowie"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+			if track := bestCandidate(test.tracks, test.query); track != nil {
+				got = track.URI
+			}
+			if got != test.want {
+				t.Errorf("bestCandidate(%+v) = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+}
@@ -2,36 +2,105 @@ package spotify
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+	"github.com/ericflores108/spotify/ratelimit"
 )
 
 const (
 	BaseURL = "https://api.spotify.com/v1"
 )
 
+// ErrNotFound is wrapped by errors for requests Spotify answered with 404 Not Found.
+var ErrNotFound = errors.New("not found on Spotify")
+
 type AuthClient struct {
 	Client      *http.Client
 	AccessToken string
 	UserID      string
+	// TokenSource, when set, supplies and refreshes the access token instead of AccessToken.
+	TokenSource *TokenSource
+	// MaxItems caps how many items list methods read, DefaultMaxItems when 0.
+	MaxItems int
 }
 
 // Get creates and sends an authenticated GET request to the Spotify API at the specified endpoint.
 // It returns the HTTP response or an error if the request fails.
-func (c *AuthClient) Get(endpoint string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", BaseURL+endpoint, nil)
+func (c *AuthClient) Get(ctx context.Context, endpoint string) (*http.Response, error) {
+	return c.do(ctx, "GET", endpoint, nil)
+}
+
+func (c *AuthClient) Post(ctx context.Context, endpoint string, payload any) (*http.Response, error) {
+	return c.doJSON(ctx, "POST", endpoint, payload)
+}
+
+// Put sends an authenticated PUT request with the payload as JSON.
+func (c *AuthClient) Put(ctx context.Context, endpoint string, payload any) (*http.Response, error) {
+	return c.doJSON(ctx, "PUT", endpoint, payload)
+}
+
+// Delete sends an authenticated DELETE request with the payload, if any, as JSON.
+func (c *AuthClient) Delete(ctx context.Context, endpoint string, payload any) (*http.Response, error) {
+	return c.doJSON(ctx, "DELETE", endpoint, payload)
+}
+
+func (c *AuthClient) doJSON(ctx context.Context, method, endpoint string, payload any) (*http.Response, error) {
+	// Convert the payload to JSON
+	var body []byte
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		body = jsonData
+	}
+
+	return c.do(ctx, method, endpoint, body)
+}
+
+// token returns the access token to send, refreshing it first when a TokenSource is set.
+func (c *AuthClient) token(ctx context.Context) (string, error) {
+	if c.TokenSource == nil {
+		return c.AccessToken, nil
+	}
+	return c.TokenSource.Token(ctx)
+}
+
+// do sends an authenticated request. When Spotify rejects the token and a TokenSource is set,
+// the token is refreshed and the request is sent once more. Throttled requests and failed GETs
+// are retried by sendWithRetry.
+func (c *AuthClient) do(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	token, err := c.token(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get access token: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
-	resp, err := c.Client.Do(req)
+	resp, err := c.sendWithRetry(ctx, method, endpoint, body, token)
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.StatusCode == http.StatusUnauthorized && c.TokenSource != nil {
+		resp.Body.Close()
+
+		token, err = c.TokenSource.Refresh(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh access token: %w", err)
+		}
+
+		resp, err = c.sendWithRetry(ctx, method, endpoint, body, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		// Read the error body to get more details
 		bodyBytes, readErr := io.ReadAll(resp.Body)
@@ -42,49 +111,79 @@ func (c *AuthClient) Get(endpoint string) (*http.Response, error) {
 		}
 		errorBody := string(bodyBytes) // Convert body to string for better display
 
-		return nil, fmt.Errorf("request to %s failed with status %d. \nError: %v. \nResponse body: %s", endpoint, resp.StatusCode, resp.Status, errorBody)
+		err := fmt.Errorf("request to %s failed with status %d. \nError: %v. \nResponse body: %s", endpoint, resp.StatusCode, resp.Status, errorBody)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
 	}
 
-	return resp, err
+	return resp, nil
 }
 
-func (c *AuthClient) Post(endpoint string, payload any) (*http.Response, error) {
-	// Convert the payload to JSON
-	var body io.Reader
-	if payload != nil {
-		jsonData, err := json.Marshal(payload)
+// sendWithRetry sends the request once the shared rate limiter allows it. A 429 pauses every
+// client for the response's Retry-After and is then retried. A 5xx is retried with backoff, but
+// only for GETs, since a failed POST may still have been applied. When retries run out, or
+// waiting would outlast ctx, the last response is returned for the caller to report.
+func (c *AuthClient) sendWithRetry(ctx context.Context, method, endpoint string, body []byte, token string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("failed waiting to send request to %s: %w", endpoint, err)
+		}
+		metrics.Add(metricWaitMillis, time.Since(start).Milliseconds())
+
+		metrics.Add(metricRequests, 1)
+		resp, err := c.send(ctx, method, endpoint, body, token)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+			return nil, err
 		}
-		body = bytes.NewBuffer(jsonData)
-	}
 
-	req, err := http.NewRequest("POST", BaseURL+endpoint, body)
-	if err != nil {
-		return nil, err
+		var wait time.Duration
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			metrics.Add(metricThrottled, 1)
+			wait = ratelimit.RetryAfter(resp.Header, ratelimit.Backoff(attempt, baseBackoff, maxBackoff))
+			limiter.Pause(wait)
+		case resp.StatusCode >= 500 && method == http.MethodGet:
+			metrics.Add(metricServerErrors, 1)
+			wait = ratelimit.Backoff(attempt, baseBackoff, maxBackoff)
+		default:
+			return resp, nil
+		}
+
+		if attempt == maxRetries || !ratelimit.CanWait(ctx, wait, maxRetryAfter) {
+			return resp, nil
+		}
+
+		logger.LogInfo(ctx, "Spotify request to %s failed with status %d, retrying in %s", endpoint, resp.StatusCode, wait)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		metrics.Add(metricRetries, 1)
+		metrics.Add(metricWaitMillis, wait.Milliseconds())
+
+		if err := ratelimit.Sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("failed waiting to retry request to %s: %w", endpoint, err)
+		}
 	}
+}
 
-	// Set necessary headers
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
-	req.Header.Set("Content-Type", "application/json")
+func (c *AuthClient) send(ctx context.Context, method, endpoint string, body []byte, token string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
 
-	resp, err := c.Client.Do(req)
+	req, err := http.NewRequestWithContext(ctx, method, BaseURL+endpoint, reader)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// Read the error body to get more details
-		bodyBytes, readErr := io.ReadAll(resp.Body)
-		defer resp.Body.Close()
-
-		if readErr != nil {
-			return nil, fmt.Errorf("request to %s failed with status %d, and failed to read error body: %v", endpoint, resp.StatusCode, readErr)
-		}
-		errorBody := string(bodyBytes) // Convert body to string for better display
-
-		return nil, fmt.Errorf("request to %s failed with status %d. \nError: %v. \nResponse body: %s", endpoint, resp.StatusCode, resp.Status, errorBody)
+	// Set necessary headers
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
 
-	return resp, err
+	return c.Client.Do(req)
 }
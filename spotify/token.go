@@ -0,0 +1,95 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ericflores108/spotify/logger"
+)
+
+// expiryDelta is how long before expiry a token is refreshed, so requests never race the expiry.
+const expiryDelta = time.Minute
+
+// Token is a user's OAuth token with its expiry.
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// TokenSource hands out a user's access token and refreshes it with the refresh token before
+// it expires, or when Spotify rejects it. It is safe for concurrent use.
+type TokenSource struct {
+	ClientID     string
+	ClientSecret string
+	// OnRefresh is called with every refreshed token, e.g. to persist it.
+	OnRefresh func(Token) error
+
+	mu    sync.Mutex
+	token Token
+}
+
+func NewTokenSource(token Token, clientID, clientSecret string, onRefresh func(Token) error) *TokenSource {
+	return &TokenSource{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		OnRefresh:    onRefresh,
+		token:        token,
+	}
+}
+
+// Token returns a valid access token, refreshing it first when it is about to expire.
+// A token without an expiry is used until Spotify rejects it.
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token.Expiry.IsZero() || time.Now().Add(expiryDelta).Before(ts.token.Expiry) {
+		return ts.token.AccessToken, nil
+	}
+
+	return ts.refresh(ctx)
+}
+
+// Refresh replaces a rejected access token. If the token was already replaced by another
+// caller since stale was handed out, the current token is returned without refreshing again.
+func (ts *TokenSource) Refresh(ctx context.Context, stale string) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token.AccessToken != stale {
+		return ts.token.AccessToken, nil
+	}
+
+	return ts.refresh(ctx)
+}
+
+// refresh exchanges the refresh token for a new access token. ts.mu must be held.
+func (ts *TokenSource) refresh(ctx context.Context) (string, error) {
+	if ts.token.RefreshToken == "" {
+		return "", fmt.Errorf("access token expired and no refresh token is available")
+	}
+
+	tokenResponse, err := RefreshToken(ctx, ts.token.RefreshToken, ts.ClientID, ts.ClientSecret)
+	if err != nil {
+		return "", err
+	}
+
+	ts.token.AccessToken = tokenResponse.AccessToken
+	ts.token.Expiry = time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	// Spotify only sometimes rotates the refresh token
+	if tokenResponse.RefreshToken != "" {
+		ts.token.RefreshToken = tokenResponse.RefreshToken
+	}
+
+	// the new token is valid even if it could not be persisted
+	if ts.OnRefresh != nil {
+		if err := ts.OnRefresh(ts.token); err != nil {
+			logger.LogError(ctx, "Failed to persist refreshed token: %v", err)
+		}
+	}
+
+	return ts.token.AccessToken, nil
+}
@@ -1,56 +1,65 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
-	"strings"
 )
 
-func (c *AuthClient) GetTrackURI(trackName, artistName string) (string, error) {
-	query := url.Values{}
-
-	if artistName != "" && !strings.Contains(trackName, " by ") {
-		query.Set("q", fmt.Sprintf("track:%s artist:%s", trackName, artistName))
-	} else {
-		query.Set("q", fmt.Sprintf("track:%s", trackName))
+// GetTrack returns the track with the ID. Under a context from WithMarket, a track Spotify
+// relinks for that market is returned as the market's version.
+func (c *AuthClient) GetTrack(ctx context.Context, trackID string) (*Track, error) {
+	endpoint := fmt.Sprintf("/tracks/%s", trackID)
+	if market := MarketFromContext(ctx); market != "" {
+		endpoint += "?market=" + url.QueryEscape(market)
 	}
 
-	query.Set("type", "track")
-	query.Set("limit", "1")
-
-	endpoint := fmt.Sprintf("/search?%s", query.Encode())
-
-	resp, err := c.Get(endpoint)
+	resp, err := c.Get(ctx, endpoint)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("failed to get track: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var track Track
+	if err := json.Unmarshal(body, &track); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	var searchResponse SearchResponse
-	if err := json.Unmarshal(body, &searchResponse); err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	return &track, nil
+}
+
+// GetTrackURI returns the URI of the track matching the name and artist, or "" when Spotify
+// has no good match. See ResolveTrack.
+func (c *AuthClient) GetTrackURI(ctx context.Context, trackName, artistName string) (string, error) {
+	track, err := c.SearchTrack(ctx, trackName, artistName)
+	if err != nil {
+		return "", err
 	}
 
-	// Check if any tracks were found
-	if len(searchResponse.Tracks.Items) == 0 {
-		return "", fmt.Errorf("no tracks found for %s by %s", trackName, artistName)
+	if track == nil {
+		return "", nil
 	}
 
-	// Return the URI of the first track found
-	return searchResponse.Tracks.Items[0].URI, nil
+	return track.URI, nil
+}
+
+// SearchTrack returns the track matching the name and artist, including its album and
+// artists, or nil when Spotify has no good match. See ResolveTrack.
+func (c *AuthClient) SearchTrack(ctx context.Context, trackName, artistName string) (*Track, error) {
+	return c.ResolveTrack(ctx, TrackQuery{Name: trackName, Artist: artistName})
 }
 
 // Tracks retrieves the top tracks for the user and converts them into a TopTracksResponse
-func (c *AuthClient) TopTracks() (*TopTracksResponse, error) {
+func (c *AuthClient) TopTracks(ctx context.Context) (*TopTracksResponse, error) {
 	// Step 1: Get top items with items as `[]any`
-	topTracksRes, err := c.GetTopItems(Tracks)
+	topTracksRes, err := c.GetTopItems(ctx, Tracks)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get top tracks: %v", err)
 	}
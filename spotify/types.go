@@ -71,6 +71,7 @@ type Track struct {
 // Album represents the album details for a track.
 type Album struct {
 	AlbumType            string        `json:"album_type"`
+	AlbumGroup           string        `json:"album_group"`
 	TotalTracks          int           `json:"total_tracks"`
 	AvailableMarkets     []string      `json:"available_markets"`
 	ExternalURLs         ExternalURLs  `json:"external_urls"`
@@ -121,9 +122,16 @@ type ExternalURLS struct {
 	Spotify string `json:"spotify"`
 }
 
+// SnapshotResponse is returned by every change to a playlist's tracks. The snapshot ID names the
+// playlist's version after the change.
+type SnapshotResponse struct {
+	SnapshotID string `json:"snapshot_id"`
+}
+
 type NewPlaylistResponse struct {
 	ID           string       `json:"id"`
 	URI          string       `json:"uri"`
+	SnapshotID   string       `json:"snapshot_id"`
 	ExternalURLs ExternalURLS `json:"external_urls"`
 }
 
@@ -139,22 +147,18 @@ type Playlist struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	URI         string `json:"uri"`
+	// SnapshotID changes whenever the playlist's tracks do.
+	SnapshotID string `json:"snapshot_id"`
 }
 
 type PlaylistsResponse struct {
 	Items []Playlist `json:"items"`
 }
-type PlaylistTracksResponse struct {
-	Items []struct {
-		Track struct {
-			ID      string `json:"id"`
-			Name    string `json:"name"`
-			URI     string `json:"uri"`
-			Artists []struct {
-				Name string `json:"name"`
-			} `json:"artists"`
-		} `json:"track"`
-	} `json:"items"`
+
+// PlaylistItem is an entry of a playlist. Track is nil when the track was removed from Spotify.
+type PlaylistItem struct {
+	AddedAt string `json:"added_at"`
+	Track   *Track `json:"track"`
 }
 
 type AlbumResponse struct {
@@ -237,6 +241,17 @@ type SimplifiedAlbumDetails struct {
 	TrackNames []string `json:"track_names"`
 }
 type SearchResponse struct {
-	Tracks AlbumTracks   `json:"tracks"`
-	Albums AlbumResponse `json:"albums"`
+	Tracks TrackSearchResults `json:"tracks"`
+	Albums AlbumResponse      `json:"albums"`
+}
+
+// TrackSearchResults holds the full track objects returned by a track search, including their album.
+type TrackSearchResults struct {
+	Href     string  `json:"href"`
+	Limit    int     `json:"limit"`
+	Next     string  `json:"next"`
+	Offset   int     `json:"offset"`
+	Previous string  `json:"previous"`
+	Total    int     `json:"total"`
+	Items    []Track `json:"items"`
 }
@@ -1,36 +1,44 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 )
 
 // GetTopItems retrieves the user's top artists or tracks from Spotify, based on the specified TopType.
-// It makes an authenticated request to the "me/top/{type}" endpoint and returns a pointer to TopResponse or an error.
-func (c *AuthClient) GetTopItems(top TopType) (*TopResponse, error) {
-	resp, err := c.Get("/me/top/" + string(top))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get response: %w", err)
+// It reads the "me/top/{type}" endpoint page by page and returns up to MaxItems items in one TopResponse, or an error.
+func (c *AuthClient) GetTopItems(ctx context.Context, top TopType) (*TopResponse, error) {
+	var topResponse *TopResponse
+
+	for page, err := range Pages[any](ctx, c, "/me/top/"+string(top), c.maxItems()) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to get top %s: %w", top, err)
+		}
+
+		if topResponse == nil {
+			topResponse = &TopResponse{
+				Href:     page.Href,
+				Limit:    page.Limit,
+				Offset:   page.Offset,
+				Previous: page.Previous,
+				Total:    page.Total,
+			}
+		}
+		// Next stays set only when the items were capped before the end of the list
+		topResponse.Next = page.Next
+		topResponse.Items = append(topResponse.Items, page.Items...)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if topResponse == nil {
+		return &TopResponse{}, nil
 	}
-
-	var topResponse TopResponse
-	if err := json.Unmarshal(body, &topResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	// Return a pointer to artistsResponse and a nil error
-	return &topResponse, nil
+	return topResponse, nil
 }
 
-func (c *AuthClient) GetUser() (*MeResponse, error) {
-	resp, err := c.Get("/me")
+func (c *AuthClient) GetUser(ctx context.Context) (*MeResponse, error) {
+	resp, err := c.Get(ctx, "/me")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get response: %w", err)
 	}